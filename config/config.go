@@ -7,6 +7,19 @@ import (
         "strings"
 )
 
+// One-time sponsorship policies
+const (
+        OneTimeExclude   = "exclude"   // never show one-time sponsors
+        OneTimeRecent    = "recent"    // show one-time sponsors for OneTimeDays after payment
+        OneTimePermanent = "permanent" // always show one-time sponsors
+)
+
+// Afdian modes
+const (
+        AfdianModeSponsor = "sponsor" // summaries from the query-sponsor API
+        AfdianModeOrders  = "orders"  // a local ledger built from the query-order API
+)
+
 // Config represents the application configuration
 type Config struct {
         // Output settings
@@ -19,23 +32,79 @@ type Config struct {
         // GitHub sponsor settings
         GitHubToken          string
         GitHubLogin          string
+        GitHubAPIURL         string
         IncludePrivate       bool
         GitHubOrgs           []string
+        GitHubExportDir      string
+        GitHubExportPollSeconds int
         ExcludeSponsors      []string
         IncludeSponsors      []string
         ForceSponsorAmounts  map[string]float64
+        IncludePastSponsors  bool
+        OneTimePolicy        string
+        OneTimeDays          int
 
         // OpenCollective settings
         OpenCollectiveSlug   string
         OpenCollectiveKey    string
+        OpenCollectiveAPIURL string
 
         // Patreon settings
         PatreonToken         string
         PatreonCampaignID    string
+        PatreonAPIURL        string
+        PatreonGraceDays     int
 
         // Afdian settings
         AfdianUserID         string
         AfdianToken          string
+        AfdianAPIURL         string
+        AfdianMode           string
+
+        // Ko-fi settings
+        KofiVerificationToken string
+
+        // Liberapay settings
+        LiberapayUsername    string
+        LiberapayAPIURL      string
+
+        // Buy Me a Coffee settings
+        BuyMeACoffeeToken    string
+        BuyMeACoffeeSlug     string
+        BuyMeACoffeeAPIURL   string
+
+        // Polar settings
+        PolarToken           string
+        PolarOrganizationID  string
+        PolarAPIURL          string
+
+        // Stripe settings
+        StripeSecretKey      string
+        StripeAPIURL         string
+
+        // Manual sponsors settings
+        ManualSponsorsFile   string
+
+        // External command settings
+        SponsorsCommand        string
+        SponsorsCommandTimeout int
+
+        // Webhook settings
+        GitHubWebhookSecret         string
+        PatreonWebhookSecret        string
+        OpenCollectiveWebhookSecret string
+        WebhookDebounceSeconds      int
+
+        // HTTP settings
+        HTTPMaxRetries       int
+
+        // Provider cache settings
+        ProviderCacheMaxAgeHours int
+
+        // Timeout settings
+        ProviderTimeoutSeconds int
+        ProviderTimeouts       map[string]int // per-provider overrides of ProviderTimeoutSeconds
+        RefreshTimeoutSeconds  int
 
         // Rendering settings
         AvatarSize           int
@@ -48,6 +117,7 @@ type Config struct {
         BackgroundColor      string
         PaddingX             int
         PaddingY             int
+        PastAvatarSize       int
 }
 
 // DefaultConfig returns a default configuration
@@ -67,19 +137,54 @@ func DefaultConfig() Config {
                 BackgroundColor: "transparent",
                 PaddingX:       10,
                 PaddingY:       10,
+                PastAvatarSize: 30,
                 GitHubToken:      "",
                 GitHubLogin:      "",
+                GitHubAPIURL:     "https://api.github.com/graphql",
                 IncludePrivate:   false,
                 GitHubOrgs:       []string{},
+                GitHubExportDir:  "",
+                GitHubExportPollSeconds: 30,
                 ExcludeSponsors:  []string{},
                 IncludeSponsors:  []string{},
                 ForceSponsorAmounts: map[string]float64{},
+                IncludePastSponsors: false,
+                OneTimePolicy:    OneTimeExclude,
+                OneTimeDays:      30,
                 OpenCollectiveSlug: "",
                 OpenCollectiveKey:  "",
+                OpenCollectiveAPIURL: "https://api.opencollective.com/graphql/v2",
                 PatreonToken:      "",
                 PatreonCampaignID: "",
+                PatreonAPIURL:     "https://www.patreon.com/api/oauth2/v2",
+                PatreonGraceDays:  0,
                 AfdianUserID:      "",
                 AfdianToken:       "",
+                AfdianAPIURL:      "https://afdian.com/api/open",
+                AfdianMode:        AfdianModeSponsor,
+                KofiVerificationToken: "",
+                LiberapayUsername: "",
+                LiberapayAPIURL:   "https://liberapay.com",
+                BuyMeACoffeeToken:  "",
+                BuyMeACoffeeSlug:   "",
+                BuyMeACoffeeAPIURL: "https://developers.buymeacoffee.com/api/v1",
+                PolarToken:          "",
+                PolarOrganizationID: "",
+                PolarAPIURL:         "https://api.polar.sh/v1",
+                StripeSecretKey:     "",
+                StripeAPIURL:        "https://api.stripe.com/v1",
+                ManualSponsorsFile:  "",
+                SponsorsCommand:        "",
+                SponsorsCommandTimeout: 30,
+                GitHubWebhookSecret:         "",
+                PatreonWebhookSecret:        "",
+                OpenCollectiveWebhookSecret: "",
+                WebhookDebounceSeconds:      10,
+                HTTPMaxRetries:              3,
+                ProviderCacheMaxAgeHours:    24,
+                ProviderTimeoutSeconds:      60,
+                ProviderTimeouts:            map[string]int{},
+                RefreshTimeoutSeconds:       300,
         }
 }
 
@@ -115,6 +220,10 @@ func LoadConfig() (Config, error) {
                 config.GitHubLogin = env
         }
         
+        if env := os.Getenv("GITHUB_API_URL"); env != "" {
+                config.GitHubAPIURL = strings.TrimRight(env, "/")
+        }
+        
         if env := os.Getenv("INCLUDE_PRIVATE"); env != "" {
                 config.IncludePrivate = (strings.ToLower(env) == "true")
         }
@@ -123,6 +232,30 @@ func LoadConfig() (Config, error) {
                 config.GitHubOrgs = strings.Split(env, ",")
         }
         
+        if env := os.Getenv("GITHUB_EXPORT_DIR"); env != "" {
+                config.GitHubExportDir = env
+        }
+
+        if env := os.Getenv("GITHUB_EXPORT_POLL_SECONDS"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.GitHubExportPollSeconds = val
+                }
+        }
+        
+        if env := os.Getenv("INCLUDE_PAST_SPONSORS"); env != "" {
+                config.IncludePastSponsors = (strings.ToLower(env) == "true")
+        }
+        
+        if env := os.Getenv("ONE_TIME_POLICY"); env != "" {
+                config.OneTimePolicy = strings.ToLower(env)
+        }
+        
+        if env := os.Getenv("ONE_TIME_DAYS"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.OneTimeDays = val
+                }
+        }
+        
         if env := os.Getenv("EXCLUDE_SPONSORS"); env != "" {
                 config.ExcludeSponsors = strings.Split(env, ",")
         }
@@ -139,6 +272,10 @@ func LoadConfig() (Config, error) {
         if env := os.Getenv("OPENCOLLECTIVE_KEY"); env != "" {
                 config.OpenCollectiveKey = env
         }
+        
+        if env := os.Getenv("OPENCOLLECTIVE_API_URL"); env != "" {
+                config.OpenCollectiveAPIURL = strings.TrimRight(env, "/")
+        }
 
         // Patreon settings
         if env := os.Getenv("PATREON_TOKEN"); env != "" {
@@ -148,6 +285,16 @@ func LoadConfig() (Config, error) {
         if env := os.Getenv("PATREON_CAMPAIGN_ID"); env != "" {
                 config.PatreonCampaignID = env
         }
+        
+        if env := os.Getenv("PATREON_API_URL"); env != "" {
+                config.PatreonAPIURL = strings.TrimRight(env, "/")
+        }
+        
+        if env := os.Getenv("PATREON_GRACE_DAYS"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.PatreonGraceDays = val
+                }
+        }
 
         // Afdian settings
         if env := os.Getenv("AFDIAN_USER_ID"); env != "" {
@@ -157,6 +304,140 @@ func LoadConfig() (Config, error) {
         if env := os.Getenv("AFDIAN_TOKEN"); env != "" {
                 config.AfdianToken = env
         }
+        
+        if env := os.Getenv("AFDIAN_API_URL"); env != "" {
+                config.AfdianAPIURL = strings.TrimRight(env, "/")
+        }
+        
+        if env := os.Getenv("AFDIAN_MODE"); env != "" {
+                config.AfdianMode = strings.ToLower(env)
+        }
+
+        // Ko-fi settings
+        if env := os.Getenv("KOFI_VERIFICATION_TOKEN"); env != "" {
+                config.KofiVerificationToken = env
+        }
+
+        // Liberapay settings
+        if env := os.Getenv("LIBERAPAY_USERNAME"); env != "" {
+                config.LiberapayUsername = env
+        }
+        
+        if env := os.Getenv("LIBERAPAY_API_URL"); env != "" {
+                config.LiberapayAPIURL = strings.TrimRight(env, "/")
+        }
+
+        // Buy Me a Coffee settings
+        if env := os.Getenv("BUYMEACOFFEE_TOKEN"); env != "" {
+                config.BuyMeACoffeeToken = env
+        }
+        
+        if env := os.Getenv("BUYMEACOFFEE_SLUG"); env != "" {
+                config.BuyMeACoffeeSlug = env
+        }
+        
+        if env := os.Getenv("BUYMEACOFFEE_API_URL"); env != "" {
+                config.BuyMeACoffeeAPIURL = strings.TrimRight(env, "/")
+        }
+
+        // Polar settings
+        if env := os.Getenv("POLAR_TOKEN"); env != "" {
+                config.PolarToken = env
+        }
+        
+        if env := os.Getenv("POLAR_ORGANIZATION_ID"); env != "" {
+                config.PolarOrganizationID = env
+        }
+        
+        if env := os.Getenv("POLAR_API_URL"); env != "" {
+                config.PolarAPIURL = strings.TrimRight(env, "/")
+        }
+
+        // Stripe settings
+        if env := os.Getenv("STRIPE_SECRET_KEY"); env != "" {
+                config.StripeSecretKey = env
+        }
+        
+        if env := os.Getenv("STRIPE_API_URL"); env != "" {
+                config.StripeAPIURL = strings.TrimRight(env, "/")
+        }
+
+        // Manual sponsors settings
+        if env := os.Getenv("SPONSORS_FILE"); env != "" {
+                config.ManualSponsorsFile = env
+        }
+
+        // External command settings
+        if env := os.Getenv("SPONSORS_COMMAND"); env != "" {
+                config.SponsorsCommand = env
+        }
+        
+        if env := os.Getenv("SPONSORS_COMMAND_TIMEOUT"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.SponsorsCommandTimeout = val
+                }
+        }
+
+        // Webhook settings
+        if env := os.Getenv("GITHUB_WEBHOOK_SECRET"); env != "" {
+                config.GitHubWebhookSecret = env
+        }
+        
+        if env := os.Getenv("PATREON_WEBHOOK_SECRET"); env != "" {
+                config.PatreonWebhookSecret = env
+        }
+        
+        if env := os.Getenv("OPENCOLLECTIVE_WEBHOOK_SECRET"); env != "" {
+                config.OpenCollectiveWebhookSecret = env
+        }
+        
+        if env := os.Getenv("WEBHOOK_DEBOUNCE_SECONDS"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.WebhookDebounceSeconds = val
+                }
+        }
+
+        // HTTP settings
+        if env := os.Getenv("HTTP_MAX_RETRIES"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.HTTPMaxRetries = val
+                }
+        }
+
+        // Provider cache settings
+        if env := os.Getenv("PROVIDER_CACHE_MAX_AGE_HOURS"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.ProviderCacheMaxAgeHours = val
+                }
+        }
+
+        // Timeout settings
+        if env := os.Getenv("PROVIDER_TIMEOUT_SECONDS"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.ProviderTimeoutSeconds = val
+                }
+        }
+        
+        if env := os.Getenv("PROVIDER_TIMEOUTS"); env != "" {
+                for _, entry := range strings.Split(env, ",") {
+                        entry = strings.TrimSpace(entry)
+                        if entry == "" {
+                                continue
+                        }
+                        name, seconds, found := strings.Cut(entry, "=")
+                        val, err := strconv.Atoi(strings.TrimSpace(seconds))
+                        if !found || err != nil {
+                                return config, fmt.Errorf("invalid PROVIDER_TIMEOUTS entry %q (expected name=seconds)", entry)
+                        }
+                        config.ProviderTimeouts[strings.ToLower(strings.TrimSpace(name))] = val
+                }
+        }
+        
+        if env := os.Getenv("REFRESH_TIMEOUT_SECONDS"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.RefreshTimeoutSeconds = val
+                }
+        }
 
         // Rendering settings
         if env := os.Getenv("AVATAR_SIZE"); env != "" {
@@ -211,6 +492,12 @@ func LoadConfig() (Config, error) {
                 }
         }
 
+        if env := os.Getenv("PAST_AVATAR_SIZE"); env != "" {
+                if val, err := strconv.Atoi(env); err == nil {
+                        config.PastAvatarSize = val
+                }
+        }
+
         // Create SVG template if not provided
         if config.SVGTemplate == "" {
                 config.SVGTemplate = DefaultSVGTemplate()
@@ -238,45 +525,127 @@ func DefaultSVGTemplate() string {
       <image xlink:href="{{.Avatar}}" class="avatar" width="{{.Size}}" height="{{.Size}}" x="0" y="0" />
     </g>
     {{end}}
+    {{if .PastSponsors}}
+    <text x="0" y="{{.PastTitleY}}" font-family="{{.FontFamily}}" font-size="{{.FontSize}}" fill="#888">Past Sponsors</text>
+    {{range .PastSponsors}}
+    <g transform="translate({{.X}}, {{.Y}})" opacity="0.75">
+      <title>{{.Name}}</title>
+      <image xlink:href="{{.Avatar}}" width="{{.Size}}" height="{{.Size}}" x="0" y="0" />
+    </g>
+    {{end}}
+    {{end}}
   </g>
 </svg>`
 }
 
 
 
-// ValidateConfig ensures the configuration is valid
+// ValidateConfig ensures the configuration is valid. Only settings that
+// would otherwise silently fall back to a default are rejected; incomplete
+// platform settings are reported by Warnings instead.
 func (c *Config) ValidateConfig() error {
         var errors []string
 
+        // Check one-time policy
+        switch c.OneTimePolicy {
+        case OneTimeExclude, OneTimeRecent, OneTimePermanent:
+        default:
+                errors = append(errors, fmt.Sprintf("Unknown one-time policy %q (expected %s, %s or %s)", c.OneTimePolicy, OneTimeExclude, OneTimeRecent, OneTimePermanent))
+        }
+
+        if c.OneTimePolicy == OneTimeRecent && c.OneTimeDays <= 0 {
+                errors = append(errors, "One-time policy is recent but the number of days is not positive")
+        }
+
+        // Check GitHub export polling
+        if c.GitHubExportPollSeconds < 0 {
+                errors = append(errors, "GitHub export poll interval must not be negative")
+        }
+
+        // Check Afdian mode
+        if c.AfdianMode != AfdianModeSponsor && c.AfdianMode != AfdianModeOrders {
+                errors = append(errors, fmt.Sprintf("Unknown Afdian mode %q (expected %s or %s)", c.AfdianMode, AfdianModeSponsor, AfdianModeOrders))
+        }
+
+        // Check external command configuration
+        if c.SponsorsCommand != "" && c.SponsorsCommandTimeout <= 0 {
+                errors = append(errors, "Sponsors command provided but the timeout is not positive")
+        }
+
+        // Check webhook configuration
+        if c.WebhookDebounceSeconds < 0 {
+                errors = append(errors, "Webhook debounce delay must not be negative")
+        }
+
+        // Check HTTP configuration
+        if c.HTTPMaxRetries < 0 {
+                errors = append(errors, "HTTP max retries must not be negative")
+        }
+
+        // Check timeout configuration
+        if c.ProviderTimeoutSeconds <= 0 {
+                errors = append(errors, "Provider timeout must be positive")
+        }
+
+        for name, seconds := range c.ProviderTimeouts {
+                if seconds <= 0 {
+                        errors = append(errors, fmt.Sprintf("Timeout of provider %s must be positive", name))
+                }
+        }
+
+        if c.RefreshTimeoutSeconds <= 0 {
+                errors = append(errors, "Refresh timeout must be positive")
+        }
+
+        // Return combined errors if any
+        if len(errors) > 0 {
+                return fmt.Errorf("configuration validation failed:\n- %s", strings.Join(errors, "\n- "))
+        }
+
+        return nil
+}
+
+// Warnings reports incomplete platform settings. These do not stop the
+// server, since the affected platform simply fails or is skipped.
+func (c *Config) Warnings() []string {
+        var warnings []string
+
         // Check if we have any source of sponsors
-        if c.GitHubToken == "" && c.OpenCollectiveSlug == "" && c.PatreonToken == "" && c.AfdianUserID == "" {
-                errors = append(errors, "No sponsor source configured (GitHub, OpenCollective, Patreon, or Afdian)")
+        if c.GitHubToken == "" && c.GitHubExportDir == "" && c.OpenCollectiveSlug == "" && c.PatreonToken == "" && c.AfdianUserID == "" &&
+                c.KofiVerificationToken == "" && c.LiberapayUsername == "" && c.BuyMeACoffeeToken == "" && c.PolarToken == "" &&
+                c.StripeSecretKey == "" && c.ManualSponsorsFile == "" && c.SponsorsCommand == "" {
+                warnings = append(warnings, "No sponsor source configured (GitHub, OpenCollective, Patreon, Afdian, Ko-fi, Liberapay, Buy Me a Coffee, Polar, Stripe, GitHub Sponsors exports, a sponsors file, or a sponsors command)")
         }
 
         // Check GitHub configuration
-        if c.GitHubToken != "" && c.GitHubLogin == "" {
-                errors = append(errors, "GitHub token provided but GitHub login is missing")
+        if c.GitHubToken != "" && c.GitHubLogin == "" && len(c.GitHubOrgs) == 0 {
+                warnings = append(warnings, "GitHub token provided but neither GitHub login nor organizations are set")
+        }
+
+        // Check Buy Me a Coffee configuration
+        if c.BuyMeACoffeeToken != "" && c.BuyMeACoffeeSlug == "" {
+                warnings = append(warnings, "Buy Me a Coffee token provided but page slug is missing")
+        }
+
+        // Check Polar configuration
+        if c.PolarToken != "" && c.PolarOrganizationID == "" {
+                warnings = append(warnings, "Polar token provided but organization ID is missing")
         }
 
-        // Check OpenCollective configuration
+        // Check OpenCollective configuration; public data needs no key
         if c.OpenCollectiveSlug != "" && c.OpenCollectiveKey == "" {
-                errors = append(errors, "OpenCollective slug provided but API key is missing")
+                warnings = append(warnings, "OpenCollective slug provided without an API key, only public data will be fetched")
         }
 
         // Check Patreon configuration
         if c.PatreonToken != "" && c.PatreonCampaignID == "" {
-                errors = append(errors, "Patreon token provided but campaign ID is missing")
+                warnings = append(warnings, "Patreon token provided but campaign ID is missing")
         }
         
         // Check Afdian configuration
         if c.AfdianUserID != "" && c.AfdianToken == "" {
-                errors = append(errors, "Afdian user ID provided but token is missing")
+                warnings = append(warnings, "Afdian user ID provided but token is missing")
         }
 
-        // Return combined errors if any
-        if len(errors) > 0 {
-                return fmt.Errorf("configuration validation failed:\n- %s", strings.Join(errors, "\n- "))
-        }
-
-        return nil
+        return warnings
 }
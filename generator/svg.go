@@ -2,6 +2,7 @@ package generator
 
 import (
         "bytes"
+        "context"
         "fmt"
         "log"
         "math"
@@ -26,6 +27,8 @@ type SVGData struct {
         PaddingX        int
         PaddingY        int
         Sponsors        []SponsorData
+        PastSponsors    []SponsorData
+        PastTitleY      int
 }
 
 // SponsorData represents a sponsor in the SVG
@@ -43,8 +46,9 @@ type SponsorData struct {
         AmountY int
 }
 
-// GenerateSVG generates an SVG file for the sponsors
-func GenerateSVG(allSponsors []sponsors.Sponsor, cfg config.Config, outputPath string) error {
+// GenerateSVG generates an SVG file for the sponsors. Avatar downloads are
+// abandoned and no file is written when ctx is cancelled.
+func GenerateSVG(ctx context.Context, allSponsors []sponsors.Sponsor, cfg config.Config, outputPath string) error {
         // Ensure default avatar exists
         if _, err := os.Stat(cfg.DefaultAvatar); os.IsNotExist(err) {
                 if err := createDefaultAvatar(cfg.DefaultAvatar); err != nil {
@@ -61,11 +65,16 @@ func GenerateSVG(allSponsors []sponsors.Sponsor, cfg config.Config, outputPath s
         sortedSponsors := sponsors.SortSponsors(allSponsors)
 
         // Calculate SVG dimensions and sponsor positions
-        svgData, err := calculateSVGLayout(sortedSponsors, cfg)
+        svgData, err := calculateSVGLayout(ctx, sortedSponsors, cfg)
         if err != nil {
                 return fmt.Errorf("failed to calculate SVG layout: %w", err)
         }
 
+        // Do not replace the current SVG with one missing avatars
+        if err := ctx.Err(); err != nil {
+                return err
+        }
+
         // Parse template
         tmpl, err := template.New("svg").Parse(cfg.SVGTemplate)
         if err != nil {
@@ -86,8 +95,10 @@ func GenerateSVG(allSponsors []sponsors.Sponsor, cfg config.Config, outputPath s
         return nil
 }
 
-// calculateSVGLayout calculates the positions of sponsors in the SVG
-func calculateSVGLayout(sortedSponsors []sponsors.Sponsor, cfg config.Config) (SVGData, error) {
+// calculateSVGLayout calculates the positions of sponsors in the SVG.
+// Past sponsors are laid out in a separate block below the current ones
+// using the smaller PastAvatarSize.
+func calculateSVGLayout(ctx context.Context, sortedSponsors []sponsors.Sponsor, cfg config.Config) (SVGData, error) {
         svgData := SVGData{
                 Width:           cfg.SVGWidth,
                 Height:          100, // Initial height, will be updated
@@ -102,12 +113,48 @@ func calculateSVGLayout(sortedSponsors []sponsors.Sponsor, cfg config.Config) (S
                 Sponsors:        []SponsorData{},
         }
 
+        // Split current and past sponsors, keeping the sort order
+        var activeSponsors, pastSponsors []sponsors.Sponsor
+        for _, sponsor := range sortedSponsors {
+                if sponsor.IsPast() {
+                        pastSponsors = append(pastSponsors, sponsor)
+                } else {
+                        activeSponsors = append(activeSponsors, sponsor)
+                }
+        }
+
+        avatarSize := cfg.AvatarSize // Use default avatar size for all sponsors
+        rowY := cfg.PaddingY + 10    // Small padding from top
+
+        var maxY int
+        svgData.Sponsors, maxY = layoutSponsors(ctx, activeSponsors, cfg, avatarSize, rowY)
+
+        if len(pastSponsors) > 0 {
+                pastAvatarSize := cfg.PastAvatarSize
+                if pastAvatarSize <= 0 {
+                        pastAvatarSize = avatarSize
+                }
+
+                // Leave room for the section title between both blocks
+                svgData.PastTitleY = maxY + cfg.AvatarMargin + cfg.FontSize*2
+                svgData.PastSponsors, maxY = layoutSponsors(ctx, pastSponsors, cfg, pastAvatarSize, svgData.PastTitleY+cfg.FontSize/2)
+        }
+
+        // Update SVG height
+        svgData.Height = maxY + cfg.PaddingY + avatarSize
+
+        return svgData, nil
+}
+
+// layoutSponsors places sponsors in rows starting at startY and returns
+// their positions along with the bottom edge of the last row
+func layoutSponsors(ctx context.Context, sortedSponsors []sponsors.Sponsor, cfg config.Config, avatarSize, startY int) ([]SponsorData, int) {
+        result := []SponsorData{}
+
         // Calculate positions for sponsors
         maxY := 0
         currentX := cfg.PaddingX
-        rowY := cfg.PaddingY + 10 // Small padding from top
-
-        avatarSize := cfg.AvatarSize // Use default avatar size for all sponsors
+        rowY := startY
 
         for _, sponsor := range sortedSponsors {
                 // Skip to next row if this sponsor doesn't fit
@@ -117,20 +164,20 @@ func calculateSVGLayout(sortedSponsors []sponsors.Sponsor, cfg config.Config) (S
                 }
 
                 // Prepare avatar URL
-                avatarURL := sponsor.AvatarURL
-                if avatarURL == "" {
-                        avatarURL = cfg.DefaultAvatar
-                }
+                avatarURL := sponsors.AvatarOrDefault(sponsor.AvatarURL, cfg)
                 
                 // Download and embed the avatar image
-                embeddedAvatar, err := utils.DownloadImage(avatarURL, cfg.CacheDir)
+                embeddedAvatar, err := utils.DownloadImage(ctx, avatarURL, cfg.CacheDir)
                 if err != nil {
                         log.Printf("Failed to download avatar for %s: %v, using default avatar", sponsor.Name, err)
-                        embeddedAvatar, _ = utils.DownloadImage(cfg.DefaultAvatar, cfg.CacheDir)
+                        embeddedAvatar, _ = utils.DownloadImage(ctx, cfg.DefaultAvatar, cfg.CacheDir)
                 }
 
-                // Format amount string
+                // Format amount string, one-time sponsors show their total
                 amountStr := fmt.Sprintf("%.2f", sponsor.MonthlyAmount)
+                if sponsor.IsOneTime {
+                        amountStr = fmt.Sprintf("%.2f", sponsor.OneTimeAmount)
+                }
 
                 // Create sponsor data
                 sponsorData := SponsorData{
@@ -147,17 +194,14 @@ func calculateSVGLayout(sortedSponsors []sponsors.Sponsor, cfg config.Config) (S
                         AmountY: rowY + avatarSize/2 + cfg.FontSize + 2,
                 }
 
-                svgData.Sponsors = append(svgData.Sponsors, sponsorData)
+                result = append(result, sponsorData)
 
                 // Update position for next sponsor
                 currentX += avatarSize + cfg.AvatarMargin
                 maxY = int(math.Max(float64(maxY), float64(rowY+avatarSize)))
         }
 
-        // Update SVG height
-        svgData.Height = maxY + cfg.PaddingY + avatarSize
-
-        return svgData, nil
+        return result, maxY
 }
 
 // createDefaultAvatar creates a default SVG avatar
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+        "context"
         "encoding/json"
         "fmt"
         "log"
@@ -18,15 +19,39 @@ import (
 // Handler manages HTTP handlers for the sponsorkit server
 type Handler struct {
         Config         config.Config
+        ctx            context.Context // cancelled when the server shuts down
         lastGeneration time.Time
-        sponsors       []sponsors.Sponsor
+        lastFetch      time.Time
+        sponsors       []sponsors.Sponsor       // unfiltered sponsors from the last fetch
+        staleProviders []sponsors.StaleProvider // providers served from their cache in the last fetch
+        renderTimer    *time.Timer
         mutex          sync.RWMutex
+
+        // refreshMutex serializes fetches and renders. It is held while
+        // providers are queried, so h.mutex stays free for webhooks.
+        refreshMutex sync.Mutex
+        fetching     bool
+        // fetchInvalidated is set when a change arrives that the running
+        // fetch may have missed
+        fetchInvalidated bool
+        // pendingChanges holds webhook changes received during the running
+        // fetch
+        pendingChanges []func([]sponsors.Sponsor) ([]sponsors.Sponsor, bool)
+}
+
+// generationStatus is written next to sponsors.json so that consumers can
+// tell which platforms were served from a stale cache
+type generationStatus struct {
+        GeneratedAt    string                   `json:"generatedAt"`
+        StaleProviders []sponsors.StaleProvider `json:"staleProviders"`
 }
 
-// NewHandler creates a new handler with the given configuration
-func NewHandler(cfg config.Config) *Handler {
+// NewHandler creates a new handler with the given configuration. Refreshes
+// in progress are cancelled when ctx is done.
+func NewHandler(ctx context.Context, cfg config.Config) *Handler {
         return &Handler{
                 Config:         cfg,
+                ctx:            ctx,
                 lastGeneration: time.Time{},
                 sponsors:       []sponsors.Sponsor{},
                 mutex:          sync.RWMutex{},
@@ -125,7 +150,7 @@ func (h *Handler) SVGHandler(w http.ResponseWriter, r *http.Request) {
         // Check if regeneration is needed
         if h.shouldRegenerate() {
                 h.mutex.RUnlock()
-                if err := h.GenerateSponsors(); err != nil {
+                if err := h.regenerateForRequest(r); err != nil {
                         h.mutex.RLock()
                         http.Error(w, "Failed to generate sponsor data", http.StatusInternalServerError)
                         return
@@ -153,7 +178,7 @@ func (h *Handler) JSONHandler(w http.ResponseWriter, r *http.Request) {
         // Check if regeneration is needed
         if h.shouldRegenerate() {
                 h.mutex.RUnlock()
-                if err := h.GenerateSponsors(); err != nil {
+                if err := h.regenerateForRequest(r); err != nil {
                         h.mutex.RLock()
                         http.Error(w, "Failed to generate sponsor data", http.StatusInternalServerError)
                         return
@@ -181,7 +206,7 @@ func (h *Handler) JPEGHandler(w http.ResponseWriter, r *http.Request) {
         // Check if regeneration is needed
         if h.shouldRegenerate() {
                 h.mutex.RUnlock()
-                if err := h.GenerateSponsors(); err != nil {
+                if err := h.regenerateForRequest(r); err != nil {
                         h.mutex.RLock()
                         http.Error(w, "Failed to generate sponsor data", http.StatusInternalServerError)
                         return
@@ -224,7 +249,7 @@ func (h *Handler) PNGHandler(w http.ResponseWriter, r *http.Request) {
         // Check if regeneration is needed
         if h.shouldRegenerate() {
                 h.mutex.RUnlock()
-                if err := h.GenerateSponsors(); err != nil {
+                if err := h.regenerateForRequest(r); err != nil {
                         h.mutex.RLock()
                         http.Error(w, "Failed to generate sponsor data", http.StatusInternalServerError)
                         return
@@ -267,7 +292,7 @@ func (h *Handler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        if err := h.GenerateSponsors(); err != nil {
+        if err := h.regenerateForRequest(r); err != nil {
                 http.Error(w, "Failed to refresh sponsor data: "+err.Error(), http.StatusInternalServerError)
                 return
         }
@@ -276,11 +301,55 @@ func (h *Handler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
         http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
-// GenerateSponsors fetches sponsor data and generates SVG and JSON files
-func (h *Handler) GenerateSponsors() error {
-        h.mutex.Lock()
-        defer h.mutex.Unlock()
+// GenerateSponsors fetches sponsor data and generates SVG and JSON files.
+// The refresh stops when ctx is cancelled or the refresh timeout expires.
+// Callers pass the handler's base context, or one derived from it, so that
+// shutdown cancels the refresh.
+func (h *Handler) GenerateSponsors(ctx context.Context) error {
+        ctx, cancel := h.refreshContext(ctx)
+        defer cancel()
+
+        h.refreshMutex.Lock()
+        defer h.refreshMutex.Unlock()
+
+        if err := h.fetchSponsors(ctx); err != nil {
+                return err
+        }
+
+        return h.renderSponsors(ctx)
+}
+
+// regenerateForRequest runs a refresh on behalf of an HTTP request. The
+// refresh is shared by every client and only bound to the handler's base
+// context, so a client that disconnects stops waiting without cancelling it.
+func (h *Handler) regenerateForRequest(r *http.Request) error {
+        done := make(chan error, 1)
+        go func() {
+                done <- h.GenerateSponsors(h.ctx)
+        }()
+
+        select {
+        case err := <-done:
+                return err
+        case <-r.Context().Done():
+                return r.Context().Err()
+        }
+}
+
+// refreshContext derives the context of a refresh from ctx, limited to
+// RefreshTimeoutSeconds
+func (h *Handler) refreshContext(ctx context.Context) (context.Context, context.CancelFunc) {
+        if h.Config.RefreshTimeoutSeconds > 0 {
+                return context.WithTimeout(ctx, time.Duration(h.Config.RefreshTimeoutSeconds)*time.Second)
+        }
+        return context.WithCancel(ctx)
+}
 
+// fetchSponsors collects the unfiltered sponsors from every enabled provider
+// into h.sponsors. The providers are queried without holding h.mutex; webhook
+// changes that arrive meanwhile are replayed on the new list. The caller must
+// hold h.refreshMutex.
+func (h *Handler) fetchSponsors(ctx context.Context) error {
         log.Println("Fetching sponsor data...")
 
         // Create cache directory if it doesn't exist
@@ -288,107 +357,66 @@ func (h *Handler) GenerateSponsors() error {
                 return fmt.Errorf("failed to create cache directory: %w", err)
         }
 
+        h.mutex.Lock()
+        h.fetching = true
+        h.fetchInvalidated = false
+        h.pendingChanges = nil
+        h.mutex.Unlock()
+
+        // Collect sponsors from every enabled provider
+        allSponsors, staleProviders, err := sponsors.FetchAll(ctx, h.Config)
+
+        h.mutex.Lock()
+        defer h.mutex.Unlock()
+
+        pending := h.pendingChanges
+        h.fetching = false
+        h.pendingChanges = nil
+
+        if err != nil {
+                return err
+        }
+
+        for _, apply := range pending {
+                allSponsors, _ = apply(allSponsors)
+        }
+
+        h.sponsors = allSponsors
+        h.staleProviders = staleProviders
+        if !h.fetchInvalidated {
+                h.lastFetch = time.Now()
+        }
+
+        return nil
+}
+
+// invalidateFetch makes the next request refetch every provider, including
+// when the running fetch started before the change
+func (h *Handler) invalidateFetch() {
+        h.mutex.Lock()
+        defer h.mutex.Unlock()
+
+        h.lastFetch = time.Time{}
+        h.fetchInvalidated = h.fetching
+}
+
+// renderSponsors applies the configured filters to a copy of h.sponsors and
+// writes the SVG and JSON files. The caller must hold h.refreshMutex.
+func (h *Handler) renderSponsors(ctx context.Context) error {
         // Create output directory if it doesn't exist
         if err := os.MkdirAll(h.Config.OutputDir, 0755); err != nil {
                 return fmt.Errorf("failed to create output directory: %w", err)
         }
 
-        // Collect sponsors from different sources
-        var allSponsors []sponsors.Sponsor
-        var wg sync.WaitGroup
-        var mu sync.Mutex
-        var errors []error
-
-        // GitHub sponsors
-        if h.Config.GitHubToken != "" && h.Config.GitHubLogin != "" {
-                wg.Add(1)
-                go func() {
-                        defer wg.Done()
-                        ghSponsors, err := sponsors.FetchGitHubSponsors(h.Config)
-                        if err != nil {
-                                mu.Lock()
-                                errors = append(errors, fmt.Errorf("GitHub sponsors: %w", err))
-                                mu.Unlock()
-                                return
-                        }
-                        mu.Lock()
-                        allSponsors = append(allSponsors, ghSponsors...)
-                        mu.Unlock()
-                }()
-        }
-
-        // OpenCollective sponsors
-        if h.Config.OpenCollectiveSlug != "" {
-                wg.Add(1)
-                go func() {
-                        defer wg.Done()
-                        ocSponsors, err := sponsors.FetchOpenCollectiveSponsors(h.Config)
-                        if err != nil {
-                                mu.Lock()
-                                errors = append(errors, fmt.Errorf("OpenCollective sponsors: %w", err))
-                                mu.Unlock()
-                                return
-                        }
-                        mu.Lock()
-                        allSponsors = append(allSponsors, ocSponsors...)
-                        mu.Unlock()
-                }()
-        }
-
-        // Patreon sponsors
-        if h.Config.PatreonToken != "" && h.Config.PatreonCampaignID != "" {
-                wg.Add(1)
-                go func() {
-                        defer wg.Done()
-                        patreonSponsors, err := sponsors.FetchPatreonSponsors(h.Config)
-                        if err != nil {
-                                mu.Lock()
-                                errors = append(errors, fmt.Errorf("Patreon sponsors: %w", err))
-                                mu.Unlock()
-                                return
-                        }
-                        mu.Lock()
-                        allSponsors = append(allSponsors, patreonSponsors...)
-                        mu.Unlock()
-                }()
-        }
-
-        // Afdian sponsors
-        if h.Config.AfdianUserID != "" && h.Config.AfdianToken != "" {
-                wg.Add(1)
-                go func() {
-                        defer wg.Done()
-                        afdianSponsors, err := sponsors.FetchAfdianSponsors(h.Config)
-                        if err != nil {
-                                mu.Lock()
-                                errors = append(errors, fmt.Errorf("Afdian sponsors: %w", err))
-                                mu.Unlock()
-                                return
-                        }
-                        mu.Lock()
-                        allSponsors = append(allSponsors, afdianSponsors...)
-                        mu.Unlock()
-                }()
-        }
-
-        // Wait for all fetchers to complete
-        wg.Wait()
-
-        // Check for errors
-        if len(errors) > 0 {
-                errorMsg := "Errors fetching sponsors:\n"
-                for _, err := range errors {
-                        errorMsg += "- " + err.Error() + "\n"
-                }
-                // If we have some sponsors, continue despite errors
-                if len(allSponsors) == 0 {
-                        return fmt.Errorf(errorMsg)
-                }
-                log.Println(errorMsg)
+        h.mutex.RLock()
+        cached := append([]sponsors.Sponsor(nil), h.sponsors...)
+        status := generationStatus{
+                StaleProviders: append([]sponsors.StaleProvider{}, h.staleProviders...),
         }
+        h.mutex.RUnlock()
 
         // Apply exclusions and inclusions from config
-        allSponsors = sponsors.ApplyFilters(allSponsors, h.Config)
+        allSponsors := sponsors.ApplyFilters(cached, h.Config)
 
         // Override amounts if specified in config
         for i, sponsor := range allSponsors {
@@ -401,7 +429,7 @@ func (h *Handler) GenerateSponsors() error {
 
         // Generate SVG
         svgPath := filepath.Join(h.Config.OutputDir, "sponsors.svg")
-        if err := generator.GenerateSVG(allSponsors, h.Config, svgPath); err != nil {
+        if err := generator.GenerateSVG(ctx, allSponsors, h.Config, svgPath); err != nil {
                 return fmt.Errorf("failed to generate SVG: %w", err)
         }
         
@@ -435,20 +463,70 @@ func (h *Handler) GenerateSponsors() error {
                 return fmt.Errorf("failed to encode JSON: %w", err)
         }
 
+        // Generate status JSON
+        status.GeneratedAt = time.Now().Format(time.RFC3339)
+        if err := writeStatus(filepath.Join(h.Config.OutputDir, "sponsors_status.json"), status); err != nil {
+                return err
+        }
+
         // Update state
-        h.sponsors = allSponsors
+        h.mutex.Lock()
         h.lastGeneration = time.Now()
+        h.mutex.Unlock()
 
         log.Printf("Generated sponsors SVG and JSON successfully (PNG and JPEG will be generated on first request)")
         return nil
 }
 
-// shouldRegenerate checks if sponsor data should be regenerated
+// writeStatus writes the generation status JSON to path
+func writeStatus(path string, status generationStatus) error {
+        data, err := json.MarshalIndent(status, "", "  ")
+        if err != nil {
+                return fmt.Errorf("failed to encode status JSON: %w", err)
+        }
+
+        if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+                return fmt.Errorf("failed to write status JSON: %w", err)
+        }
+
+        return nil
+}
+
+// scheduleRender re-renders the cached sponsors once no further changes
+// have arrived for the configured debounce delay, so a burst of webhooks
+// results in a single render. The caller must hold h.mutex.
+func (h *Handler) scheduleRender() {
+        delay := time.Duration(h.Config.WebhookDebounceSeconds) * time.Second
+
+        if h.renderTimer != nil {
+                h.renderTimer.Stop()
+        }
+
+        h.renderTimer = time.AfterFunc(delay, func() {
+                ctx, cancel := h.refreshContext(h.ctx)
+                defer cancel()
+
+                h.mutex.Lock()
+                h.renderTimer = nil
+                h.mutex.Unlock()
+
+                h.refreshMutex.Lock()
+                defer h.refreshMutex.Unlock()
+
+                if err := h.renderSponsors(ctx); err != nil {
+                        log.Printf("Warning: Failed to render sponsors after webhook: %v", err)
+                }
+        })
+}
+
+// shouldRegenerate checks if sponsor data should be refetched. Renders
+// triggered by webhooks do not postpone the refetch, so it is based on the
+// last fetch rather than the last render.
 func (h *Handler) shouldRegenerate() bool {
-        if h.lastGeneration.IsZero() {
+        if h.lastFetch.IsZero() || h.lastGeneration.IsZero() {
                 return true
         }
 
         refreshInterval := time.Duration(h.Config.RefreshMinutes) * time.Minute
-        return time.Since(h.lastGeneration) > refreshInterval
+        return time.Since(h.lastFetch) > refreshInterval
 }
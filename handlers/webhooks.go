@@ -0,0 +1,214 @@
+package handlers
+
+import (
+        "crypto/hmac"
+        "crypto/md5"
+        "crypto/sha256"
+        "crypto/subtle"
+        "encoding/hex"
+        "encoding/json"
+        "hash"
+        "io"
+        "log"
+        "net/http"
+        "strings"
+
+        "sponsorgen/sponsors"
+)
+
+// maxWebhookBody limits the size of webhook payloads that are read
+const maxWebhookBody = 1 << 20
+
+// KofiWebhookHandler receives Ko-fi webhooks and records them in the Ko-fi ledger
+func (h *Handler) KofiWebhookHandler(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+                http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+                return
+        }
+
+        if h.Config.KofiVerificationToken == "" {
+                http.Error(w, "Ko-fi webhook not configured", http.StatusNotFound)
+                return
+        }
+
+        // Ko-fi posts the event as JSON in the "data" form field
+        var event sponsors.KofiEvent
+        if err := json.Unmarshal([]byte(r.FormValue("data")), &event); err != nil {
+                http.Error(w, "Invalid Ko-fi payload", http.StatusBadRequest)
+                return
+        }
+
+        if subtle.ConstantTimeCompare([]byte(event.VerificationToken), []byte(h.Config.KofiVerificationToken)) != 1 {
+                http.Error(w, "Invalid verification token", http.StatusUnauthorized)
+                return
+        }
+
+        if err := sponsors.AppendKofiEvent(h.Config, event); err != nil {
+                log.Printf("Failed to record Ko-fi event: %v", err)
+                http.Error(w, "Failed to record Ko-fi event", http.StatusInternalServerError)
+                return
+        }
+
+        log.Printf("Recorded Ko-fi %s %s", event.Type, event.KofiTransactionID)
+
+        // Refetch on the next request
+        h.invalidateFetch()
+
+        w.WriteHeader(http.StatusOK)
+}
+
+// GitHubWebhookHandler receives GitHub Sponsors webhooks and applies
+// sponsorship changes to the cached sponsors without refetching
+func (h *Handler) GitHubWebhookHandler(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+                http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+                return
+        }
+
+        if h.Config.GitHubWebhookSecret == "" {
+                http.Error(w, "GitHub webhook not configured", http.StatusNotFound)
+                return
+        }
+
+        body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+        if err != nil {
+                http.Error(w, "Failed to read request body", http.StatusBadRequest)
+                return
+        }
+
+        if !validHMACSignature(sha256.New, h.Config.GitHubWebhookSecret, body, strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")) {
+                http.Error(w, "Invalid signature", http.StatusUnauthorized)
+                return
+        }
+
+        eventType := r.Header.Get("X-GitHub-Event")
+        if eventType != "sponsorship" {
+                // Acknowledge pings and events we do not handle
+                w.WriteHeader(http.StatusOK)
+                return
+        }
+
+        var event sponsors.GitHubSponsorshipEvent
+        if err := json.Unmarshal(body, &event); err != nil {
+                http.Error(w, "Invalid GitHub payload", http.StatusBadRequest)
+                return
+        }
+
+        log.Printf("Received GitHub sponsorship %s from %s", event.Action, event.Sponsorship.Sponsor.Login)
+
+        h.applySponsorChange(w, func(list []sponsors.Sponsor) ([]sponsors.Sponsor, bool) {
+                return sponsors.ApplyGitHubSponsorshipEvent(h.Config, list, event)
+        })
+}
+
+// PatreonWebhookHandler receives Patreon members webhooks and applies pledge
+// changes to the cached sponsors without refetching
+func (h *Handler) PatreonWebhookHandler(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+                http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+                return
+        }
+
+        if h.Config.PatreonWebhookSecret == "" {
+                http.Error(w, "Patreon webhook not configured", http.StatusNotFound)
+                return
+        }
+
+        body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+        if err != nil {
+                http.Error(w, "Failed to read request body", http.StatusBadRequest)
+                return
+        }
+
+        // Patreon signs the body with HMAC-MD5
+        if !validHMACSignature(md5.New, h.Config.PatreonWebhookSecret, body, r.Header.Get("X-Patreon-Signature")) {
+                http.Error(w, "Invalid signature", http.StatusUnauthorized)
+                return
+        }
+
+        var event sponsors.PatreonWebhookEvent
+        if err := json.Unmarshal(body, &event); err != nil {
+                http.Error(w, "Invalid Patreon payload", http.StatusBadRequest)
+                return
+        }
+
+        eventType := r.Header.Get("X-Patreon-Event")
+        log.Printf("Received Patreon %s for member %s", eventType, event.Data.ID)
+
+        h.applySponsorChange(w, func(list []sponsors.Sponsor) ([]sponsors.Sponsor, bool) {
+                return sponsors.ApplyPatreonMemberEvent(h.Config, list, eventType, event)
+        })
+}
+
+// OpenCollectiveWebhookHandler receives OpenCollective webhooks and applies
+// new backers and cancellations to the cached sponsors without refetching.
+// OpenCollective does not sign webhooks, so the secret is passed in the
+// webhook URL instead.
+func (h *Handler) OpenCollectiveWebhookHandler(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+                http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+                return
+        }
+
+        if h.Config.OpenCollectiveWebhookSecret == "" {
+                http.Error(w, "OpenCollective webhook not configured", http.StatusNotFound)
+                return
+        }
+
+        if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(h.Config.OpenCollectiveWebhookSecret)) != 1 {
+                http.Error(w, "Invalid secret", http.StatusUnauthorized)
+                return
+        }
+
+        var event sponsors.OpenCollectiveWebhookEvent
+        if err := json.NewDecoder(io.LimitReader(r.Body, maxWebhookBody)).Decode(&event); err != nil {
+                http.Error(w, "Invalid OpenCollective payload", http.StatusBadRequest)
+                return
+        }
+
+        log.Printf("Received OpenCollective %s", event.Type)
+
+        h.applySponsorChange(w, func(list []sponsors.Sponsor) ([]sponsors.Sponsor, bool) {
+                return sponsors.ApplyOpenCollectiveEvent(h.Config, list, event)
+        })
+}
+
+// applySponsorChange applies a webhook change to the cached sponsors and
+// schedules a render if anything changed. A change that arrives during a
+// fetch is also replayed on the fetched sponsors, which may predate it.
+func (h *Handler) applySponsorChange(w http.ResponseWriter, apply func([]sponsors.Sponsor) ([]sponsors.Sponsor, bool)) {
+        h.mutex.Lock()
+        defer h.mutex.Unlock()
+
+        if h.fetching {
+                h.pendingChanges = append(h.pendingChanges, apply)
+        }
+
+        // Without a first fetch there is nothing to update; the next refresh
+        // will include the change
+        if h.lastFetch.IsZero() {
+                w.WriteHeader(http.StatusAccepted)
+                return
+        }
+
+        var changed bool
+        h.sponsors, changed = apply(h.sponsors)
+        if changed {
+                h.scheduleRender()
+        }
+
+        w.WriteHeader(http.StatusOK)
+}
+
+// validHMACSignature reports whether signature is the hex encoded HMAC of
+// body using secret and the given hash function
+func validHMACSignature(newHash func() hash.Hash, secret string, body []byte, signature string) bool {
+        expected, err := hex.DecodeString(signature)
+        if err != nil {
+                return false
+        }
+
+        mac := hmac.New(newHash, []byte(secret))
+        mac.Write(body)
+        return hmac.Equal(mac.Sum(nil), expected)
+}
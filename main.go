@@ -1,19 +1,27 @@
 package main
 
 import (
+        "context"
         "flag"
         "fmt"
         "log"
         "net/http"
         "os"
+        "os/signal"
+        "syscall"
         "time"
 
         "sponsorgen/config"
         "sponsorgen/handlers"
+        "sponsorgen/sponsors"
 )
 
-// scheduleMidnightRefresh sets up a scheduler to refresh sponsor data at midnight (00:00) every day
-func scheduleMidnightRefresh(handler *handlers.Handler) {
+// shutdownTimeout is how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// scheduleMidnightRefresh sets up a scheduler to refresh sponsor data at midnight (00:00) every day.
+// It returns when ctx is cancelled.
+func scheduleMidnightRefresh(ctx context.Context, handler *handlers.Handler) {
         for {
                 now := time.Now()
                 // Calculate time until next midnight
@@ -22,11 +30,17 @@ func scheduleMidnightRefresh(handler *handlers.Handler) {
                 
                 // Sleep until next midnight
                 log.Printf("Next scheduled refresh in %s at %s", duration.Round(time.Second), midnight.Format("2006-01-02 15:04:05"))
-                time.Sleep(duration)
+                timer := time.NewTimer(duration)
+                select {
+                case <-ctx.Done():
+                        timer.Stop()
+                        return
+                case <-timer.C:
+                }
                 
                 // Refresh the sponsors at midnight
                 log.Println("Executing scheduled midnight refresh...")
-                if err := handler.GenerateSponsors(); err != nil {
+                if err := handler.GenerateSponsors(ctx); err != nil {
                         log.Printf("Warning: Scheduled refresh failed: %v", err)
                 } else {
                         log.Println("Scheduled refresh completed successfully")
@@ -34,6 +48,41 @@ func scheduleMidnightRefresh(handler *handlers.Handler) {
         }
 }
 
+// watchGitHubExportDir refreshes the sponsor data whenever the exports in
+// dir change. The directory is polled every interval until ctx is cancelled.
+func watchGitHubExportDir(ctx context.Context, handler *handlers.Handler, dir string, interval time.Duration) {
+        lastState, err := sponsors.GitHubExportDirState(dir)
+        if err != nil {
+                log.Printf("Warning: Failed to scan GitHub export directory: %v", err)
+        }
+
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        for {
+                select {
+                case <-ctx.Done():
+                        return
+                case <-ticker.C:
+                }
+
+                state, err := sponsors.GitHubExportDirState(dir)
+                if err != nil {
+                        log.Printf("Warning: Failed to scan GitHub export directory: %v", err)
+                        continue
+                }
+                if state == lastState {
+                        continue
+                }
+                lastState = state
+
+                log.Println("GitHub export directory changed, refreshing...")
+                if err := handler.GenerateSponsors(ctx); err != nil {
+                        log.Printf("Warning: Refresh after GitHub export change failed: %v", err)
+                }
+        }
+}
+
 func main() {
         // Define command line flags
         port := flag.Int("port", 8000, "Port to serve on")
@@ -45,13 +94,26 @@ func main() {
                 log.Fatalf("Failed to load configuration: %v", err)
         }
 
+        // Reject unknown policies and modes instead of silently falling back
+        // to defaults
+        if err := cfg.ValidateConfig(); err != nil {
+                log.Fatalf("Invalid configuration: %v", err)
+        }
+        for _, warning := range cfg.Warnings() {
+                log.Printf("Warning: %s", warning)
+        }
+
         // Create output directory if it doesn't exist
         if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
                 log.Fatalf("Failed to create output directory: %v", err)
         }
 
+        // Cancel refreshes and shut down on SIGINT or SIGTERM
+        ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+        defer stop()
+
         // Setup HTTP handler
-        handler := handlers.NewHandler(cfg)
+        handler := handlers.NewHandler(ctx, cfg)
 
         // Register handlers
         http.HandleFunc("/", handler.IndexHandler)
@@ -60,6 +122,10 @@ func main() {
         http.HandleFunc("/sponsors.png", handler.PNGHandler)
         http.HandleFunc("/sponsors.jpg", handler.JPEGHandler)
         http.HandleFunc("/refresh", handler.RefreshHandler)
+        http.HandleFunc("/webhooks/kofi", handler.KofiWebhookHandler)
+        http.HandleFunc("/webhooks/github", handler.GitHubWebhookHandler)
+        http.HandleFunc("/webhooks/patreon", handler.PatreonWebhookHandler)
+        http.HandleFunc("/webhooks/opencollective", handler.OpenCollectiveWebhookHandler)
 
         // Serve static files
         fs := http.FileServer(http.Dir(cfg.OutputDir))
@@ -76,16 +142,38 @@ func main() {
         log.Printf("Force refresh with http://localhost:%d/refresh", *port)
 
         // Generate initial sponsor data
-        if err := handler.GenerateSponsors(); err != nil {
+        if err := handler.GenerateSponsors(ctx); err != nil {
                 log.Printf("Warning: Failed to generate initial sponsor data: %v", err)
         }
 
         // Setup daily refresh at midnight (00:00)
-        go scheduleMidnightRefresh(handler)
+        go scheduleMidnightRefresh(ctx, handler)
         log.Println("Scheduled daily refresh at 00:00")
 
+        if cfg.GitHubExportDir != "" && cfg.GitHubExportPollSeconds > 0 {
+                go watchGitHubExportDir(ctx, handler, cfg.GitHubExportDir, time.Duration(cfg.GitHubExportPollSeconds)*time.Second)
+                log.Printf("Watching %s for GitHub export changes", cfg.GitHubExportDir)
+        }
+
         // Start HTTP server
-        if err := http.ListenAndServe(addr, nil); err != nil {
+        server := &http.Server{Addr: addr}
+        serverErr := make(chan error, 1)
+        go func() {
+                serverErr <- server.ListenAndServe()
+        }()
+
+        select {
+        case err := <-serverErr:
                 log.Fatalf("Server failed: %v", err)
+        case <-ctx.Done():
+        }
+
+        // Stop accepting requests and wait for the current ones to finish
+        log.Println("Shutting down...")
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+        defer cancel()
+
+        if err := server.Shutdown(shutdownCtx); err != nil {
+                log.Printf("Warning: Server shutdown failed: %v", err)
         }
 }
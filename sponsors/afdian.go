@@ -2,6 +2,7 @@ package sponsors
 
 import (
         "bytes"
+        "context"
         "crypto/md5"
         "encoding/hex"
         "encoding/json"
@@ -15,6 +16,23 @@ import (
         "sponsorgen/config"
 )
 
+func init() {
+        Register(afdianProvider{})
+}
+
+// afdianProvider fetches sponsors from Afdian
+type afdianProvider struct{}
+
+func (afdianProvider) Name() string { return "afdian" }
+
+func (afdianProvider) Enabled(cfg config.Config) bool {
+        return cfg.AfdianUserID != "" && cfg.AfdianToken != ""
+}
+
+func (afdianProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchAfdianSponsors(ctx, cfg)
+}
+
 // AfdianSponsor represents data returned from Afdian API
 type AfdianSponsor struct {
         SponsorPlans []AfdianPlan   `json:"sponsor_plans"`
@@ -39,6 +57,19 @@ type AfdianUserInfo struct {
         Avatar string `json:"avatar"`
 }
 
+// AfdianOrder represents an order returned by the query-order API
+type AfdianOrder struct {
+        OutTradeNo  string `json:"out_trade_no"`
+        UserID      string `json:"user_id"`
+        PlanID      string `json:"plan_id"`
+        Month       int    `json:"month"`
+        TotalAmount string `json:"total_amount"`
+        ShowAmount  string `json:"show_amount"`
+        Status      int    `json:"status"`       // 2 means paid
+        ProductType int    `json:"product_type"` // 0 for sponsorship plans, 1 for products
+        CreateTime  int64  `json:"create_time"`
+}
+
 // AfdianResponse represents the response from Afdian API
 type AfdianResponse struct {
         EC   int    `json:"ec"`
@@ -50,142 +81,325 @@ type AfdianResponse struct {
         } `json:"data"`
 }
 
-// FetchAfdianSponsors retrieves sponsors from Afdian
-func FetchAfdianSponsors(cfg config.Config) ([]Sponsor, error) {
+// AfdianOrderResponse represents the response from the query-order API
+type AfdianOrderResponse struct {
+        EC   int    `json:"ec"`
+        EM   string `json:"em"`
+        Data struct {
+                TotalCount int           `json:"total_count"`
+                TotalPage  int           `json:"total_page"`
+                List       []AfdianOrder `json:"list"`
+        } `json:"data"`
+}
+
+// afdianOrderPaid is the order status of a successful payment
+const afdianOrderPaid = 2
+
+// FetchAfdianSponsors retrieves sponsors from Afdian. The monthly amount is
+// taken from the price of the current plan; sponsors without one have their
+// recurring amount worked out from their order history. The order history
+// also decides when each sponsorship expires.
+func FetchAfdianSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
         if cfg.AfdianUserID == "" || cfg.AfdianToken == "" {
                 return nil, fmt.Errorf("Afdian user ID or token not provided")
         }
 
         log.Println("Fetching Afdian sponsors...")
 
+        client := newHTTPClient(cfg, 30*time.Second)
+
+        afdianSponsors, err := fetchAfdianSponsorList(ctx, client, cfg)
+        if err != nil {
+                return nil, err
+        }
+
+        // In orders mode the local order ledger is authoritative
+        if cfg.AfdianMode == config.AfdianModeOrders {
+                allSponsors, err := fetchAfdianLedgerSponsors(ctx, client, cfg, afdianSponsors)
+                if err != nil {
+                        return nil, err
+                }
+                log.Printf("Found %d Afdian sponsors in order ledger", len(allSponsors))
+                return allSponsors, nil
+        }
+
+        // The order history is always fetched so that every sponsor's
+        // expiry and month count are decided the same way
+        orders, err := fetchAfdianOrders(ctx, client, cfg)
+        if err != nil {
+                return nil, fmt.Errorf("fetching orders: %w", err)
+        }
+        ordersByUser := groupAfdianOrders(orders)
+
         var allSponsors []Sponsor
+        now := time.Now()
+
+        // Process sponsors
+        for _, afdianSponsor := range afdianSponsors {
+                sponsor, ok := afdianSponsorFromRecord(cfg, afdianSponsor, ordersByUser[afdianSponsor.User.UserID], now)
+                if ok {
+                        allSponsors = append(allSponsors, sponsor)
+                }
+        }
+
+        log.Printf("Found %d Afdian sponsors", len(allSponsors))
+        return allSponsors, nil
+}
+
+// fetchAfdianSponsorList retrieves every sponsor through the query-sponsor API
+func fetchAfdianSponsorList(ctx context.Context, client *http.Client, cfg config.Config) ([]AfdianSponsor, error) {
+        var afdianSponsors []AfdianSponsor
         page := 1
         totalPages := 1
 
         // Loop through pages
         for page <= totalPages {
-                // Create parameters for this page
-                params := map[string]interface{}{
-                        "page":    page,
-                        "per_page": 50, // Maximum allowed
+                var afdianResp AfdianResponse
+                if err := afdianRequest(ctx, client, cfg, "query-sponsor", page, 50, &afdianResp); err != nil {
+                        return nil, err
                 }
 
-                // Convert params to JSON string
-                paramsJSON, err := json.Marshal(params)
-                if err != nil {
-                        return nil, fmt.Errorf("encoding params: %w", err)
+                // Check response status
+                if afdianResp.EC != 200 {
+                        return nil, fmt.Errorf("API error: %s", afdianResp.EM)
                 }
 
-                // Generate timestamp
-                ts := strconv.FormatInt(time.Now().Unix(), 10)
+                afdianSponsors = append(afdianSponsors, afdianResp.Data.List...)
 
-                // Generate signature
-                signStr := fmt.Sprintf("%sparams%sts%suser_id%s", 
-                        cfg.AfdianToken, 
-                        string(paramsJSON), 
-                        ts, 
-                        cfg.AfdianUserID)
+                // Update total pages
+                totalPages = afdianResp.Data.TotalPage
                 
-                hash := md5.Sum([]byte(signStr))
-                sign := hex.EncodeToString(hash[:])
-
-                // Create request body
-                reqBody := map[string]string{
-                        "user_id": cfg.AfdianUserID,
-                        "params":  string(paramsJSON),
-                        "ts":      ts,
-                        "sign":    sign,
-                }
+                // Move to next page
+                page++
+        }
 
-                reqJSON, err := json.Marshal(reqBody)
-                if err != nil {
-                        return nil, fmt.Errorf("encoding request: %w", err)
-                }
+        return afdianSponsors, nil
+}
 
-                // Create HTTP request
-                req, err := http.NewRequest("POST", "https://afdian.com/api/open/query-sponsor", bytes.NewBuffer(reqJSON))
-                if err != nil {
-                        return nil, fmt.Errorf("creating request: %w", err)
-                }
+// afdianSponsorFromRecord converts an Afdian sponsor into a Sponsor. It
+// returns false for sponsors that should not be shown.
+func afdianSponsorFromRecord(cfg config.Config, afdianSponsor AfdianSponsor, orders []AfdianOrder, now time.Time) (Sponsor, bool) {
+        sponsor := Sponsor{
+                ID:        afdianSponsor.User.UserID,
+                Name:      afdianSponsor.User.Name,
+                Login:     afdianSponsor.User.Name, // Use name as login since Afdian doesn't have a separate login field
+                AvatarURL: afdianSponsor.User.Avatar,
+                Link:      fmt.Sprintf("https://afdian.com/@%s", afdianSponsor.User.UserID),
+                Platform:  "afdian",
+                CreatedAt: time.Unix(afdianSponsor.CreateTime, 0).Format(time.RFC3339),
+                TierName:  afdianSponsor.CurrentPlan.Name,
+                Status:    StatusActive,
+        }
 
-                req.Header.Set("Content-Type", "application/json")
+        // The latest plan order tells how many months the last payment
+        // covers. Without order history the expiry is unknown, since
+        // last_pay_time alone does not say whether months were prepaid.
+        var expiresAt time.Time
+        latest, hasPlanOrder := latestAfdianPlanOrder(orders)
+        if hasPlanOrder {
+                expiresAt = time.Unix(latest.CreateTime, 0).AddDate(0, afdianOrderMonths(latest), 0)
+        }
 
-                // Execute request
-                client := &http.Client{Timeout: 30 * time.Second}
-                resp, err := client.Do(req)
-                if err != nil {
-                        return nil, fmt.Errorf("sending request: %w", err)
+        if price := afdianPlanPrice(afdianSponsor.CurrentPlan); price > 0 {
+                sponsor.MonthlyAmount = price
+                sponsor.Months = afdianPaidMonths(orders)
+        } else if hasPlanOrder {
+                // Work out the recurring amount from the latest plan order
+                amount, _ := strconv.ParseFloat(latest.TotalAmount, 64)
+                sponsor.MonthlyAmount = amount / float64(afdianOrderMonths(latest))
+                sponsor.Months = afdianPaidMonths(orders)
+        } else {
+                // No plan at all: a one-time sponsorship
+                total, _ := strconv.ParseFloat(afdianSponsor.AllSumAmount, 64)
+                if total <= 0 || !includeOneTime(cfg, time.Unix(afdianSponsor.LastPayTime, 0)) {
+                        return Sponsor{}, false
                 }
-                defer resp.Body.Close()
+                sponsor.OneTimeAmount = total
+                sponsor.IsOneTime = true
+                return sponsor, true
+        }
 
-                // Read response
-                body, err := io.ReadAll(resp.Body)
-                if err != nil {
-                        return nil, fmt.Errorf("reading response: %w", err)
+        // Tell expired sponsors apart from current ones
+        if !expiresAt.IsZero() && now.After(expiresAt) {
+                if !cfg.IncludePastSponsors {
+                        return Sponsor{}, false
                 }
+                sponsor.Status = StatusPast
+        }
 
-                // Parse response
-                var afdianResp AfdianResponse
-                if err := json.Unmarshal(body, &afdianResp); err != nil {
-                        return nil, fmt.Errorf("parsing response: %w", err)
+        return sponsor, true
+}
+
+// fetchAfdianOrders retrieves every order through the query-order API
+func fetchAfdianOrders(ctx context.Context, client *http.Client, cfg config.Config) ([]AfdianOrder, error) {
+        return fetchAfdianOrdersUntil(ctx, client, cfg, nil)
+}
+
+// fetchAfdianOrdersUntil pages through the query-order API, newest orders
+// first. If known is not nil, paging stops after the first page that
+// contains no order for which known returns false.
+func fetchAfdianOrdersUntil(ctx context.Context, client *http.Client, cfg config.Config, known func(AfdianOrder) bool) ([]AfdianOrder, error) {
+        var orders []AfdianOrder
+        page := 1
+        totalPages := 1
+
+        for page <= totalPages {
+                var orderResp AfdianOrderResponse
+                if err := afdianRequest(ctx, client, cfg, "query-order", page, 100, &orderResp); err != nil {
+                        return nil, err
                 }
 
-                // Check response status
-                if afdianResp.EC != 200 {
-                        return nil, fmt.Errorf("API error: %s", afdianResp.EM)
+                if orderResp.EC != 200 {
+                        return nil, fmt.Errorf("API error: %s", orderResp.EM)
                 }
 
-                // Process sponsors
-                for _, afdianSponsor := range afdianResp.Data.List {
-                        // Calculate monthly amount - convert Afdian's total to monthly average
-                        monthlyAmount := 0.0
-                        if afdianSponsor.AllSumAmount != "" {
-                                totalAmount, err := strconv.ParseFloat(afdianSponsor.AllSumAmount, 64)
-                                if err == nil && totalAmount > 0 {
-                                        // If we have last pay time and create time, we can calculate a monthly average
-                                        if afdianSponsor.LastPayTime > 0 && afdianSponsor.CreateTime > 0 {
-                                                // Calculate the number of months between first and last payment
-                                                months := float64(afdianSponsor.LastPayTime-afdianSponsor.CreateTime) / (30 * 24 * 60 * 60)
-                                                if months < 1 {
-                                                        months = 1 // Minimum 1 month to avoid division by zero
-                                                }
-                                                monthlyAmount = totalAmount / months
-                                        } else {
-                                                // Fallback: assume it's a one-time donation
-                                                monthlyAmount = totalAmount
-                                        }
+                orders = append(orders, orderResp.Data.List...)
+                totalPages = orderResp.Data.TotalPage
+                page++
+
+                if known != nil {
+                        hasNew := false
+                        for _, order := range orderResp.Data.List {
+                                if !known(order) {
+                                        hasNew = true
+                                        break
                                 }
                         }
-                        
-                        // Determine tier name
-                        tierName := ""
-                        if afdianSponsor.CurrentPlan.Name != "" {
-                                tierName = afdianSponsor.CurrentPlan.Name
+                        if !hasNew {
+                                break
                         }
+                }
+        }
 
-                        // Create sponsor
-                        sponsor := Sponsor{
-                                ID:            afdianSponsor.User.UserID,
-                                Name:          afdianSponsor.User.Name,
-                                Login:         afdianSponsor.User.Name, // Use name as login since Afdian doesn't have a separate login field
-                                AvatarURL:     afdianSponsor.User.Avatar,
-                                Link:          fmt.Sprintf("https://afdian.com/@%s", afdianSponsor.User.UserID),
-                                Platform:      "afdian",
-                                MonthlyAmount: monthlyAmount,
-                                CreatedAt:     time.Unix(afdianSponsor.CreateTime, 0).Format(time.RFC3339),
-                                TierName:      tierName,
-                        }
+        return orders, nil
+}
 
-                        allSponsors = append(allSponsors, sponsor)
+// afdianRequest sends a signed request to an Afdian open API endpoint and
+// decodes the response into result
+func afdianRequest(ctx context.Context, client *http.Client, cfg config.Config, endpoint string, page, perPage int, result interface{}) error {
+        // Create parameters for this page
+        params := map[string]interface{}{
+                "page":     page,
+                "per_page": perPage,
+        }
+
+        // Convert params to JSON string
+        paramsJSON, err := json.Marshal(params)
+        if err != nil {
+                return fmt.Errorf("encoding params: %w", err)
+        }
+
+        // Generate timestamp
+        ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+        // Generate signature
+        signStr := fmt.Sprintf("%sparams%sts%suser_id%s", 
+                cfg.AfdianToken, 
+                string(paramsJSON), 
+                ts, 
+                cfg.AfdianUserID)
+        
+        hash := md5.Sum([]byte(signStr))
+        sign := hex.EncodeToString(hash[:])
+
+        // Create request body
+        reqBody := map[string]string{
+                "user_id": cfg.AfdianUserID,
+                "params":  string(paramsJSON),
+                "ts":      ts,
+                "sign":    sign,
+        }
+
+        reqJSON, err := json.Marshal(reqBody)
+        if err != nil {
+                return fmt.Errorf("encoding request: %w", err)
+        }
+
+        // Create HTTP request
+        req, err := http.NewRequestWithContext(ctx, "POST", cfg.AfdianAPIURL+"/"+endpoint, bytes.NewBuffer(reqJSON))
+        if err != nil {
+                return fmt.Errorf("creating request: %w", err)
+        }
+
+        // The query endpoints only read data and can be retried
+        req = markRetryable(req)
+
+        req.Header.Set("Content-Type", "application/json")
+
+        // Execute request
+        resp, err := client.Do(req)
+        if err != nil {
+                return fmt.Errorf("sending request: %w", err)
+        }
+        defer resp.Body.Close()
+
+        // Read response
+        body, err := io.ReadAll(resp.Body)
+        if err != nil {
+                return fmt.Errorf("reading response: %w", err)
+        }
+
+        // Parse response
+        if err := json.Unmarshal(body, result); err != nil {
+                return fmt.Errorf("parsing response: %w", err)
+        }
+
+        return nil
+}
+
+// afdianPlanPrice returns the monthly price of a plan, or 0 if it has none
+func afdianPlanPrice(plan AfdianPlan) float64 {
+        price, err := strconv.ParseFloat(plan.Price, 64)
+        if err != nil || price < 0 {
+                return 0
+        }
+        return price
+}
+
+// groupAfdianOrders groups paid orders by the user who placed them
+func groupAfdianOrders(orders []AfdianOrder) map[string][]AfdianOrder {
+        grouped := make(map[string][]AfdianOrder)
+        for _, order := range orders {
+                if order.Status != afdianOrderPaid {
+                        continue
                 }
+                grouped[order.UserID] = append(grouped[order.UserID], order)
+        }
+        return grouped
+}
 
-                // Update total pages
-                totalPages = afdianResp.Data.TotalPage
-                
-                // Move to next page
-                page++
+// afdianOrderMonths returns the number of months an order pays for, at
+// least one
+func afdianOrderMonths(order AfdianOrder) int {
+        if order.Month < 1 {
+                return 1
         }
+        return order.Month
+}
 
-        log.Printf("Found %d Afdian sponsors", len(allSponsors))
-        return allSponsors, nil
-}
\ No newline at end of file
+// latestAfdianPlanOrder returns the most recent sponsorship plan order
+func latestAfdianPlanOrder(orders []AfdianOrder) (AfdianOrder, bool) {
+        var latest AfdianOrder
+        found := false
+        for _, order := range orders {
+                if order.ProductType != 0 || order.PlanID == "" {
+                        continue
+                }
+                if !found || order.CreateTime > latest.CreateTime {
+                        latest = order
+                        found = true
+                }
+        }
+        return latest, found
+}
+
+// afdianPaidMonths returns the number of months covered by plan orders
+func afdianPaidMonths(orders []AfdianOrder) int {
+        months := 0
+        for _, order := range orders {
+                if order.ProductType == 0 && order.PlanID != "" {
+                        months += order.Month
+                }
+        }
+        return months
+}
@@ -0,0 +1,205 @@
+package sponsors
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "log"
+        "net/http"
+        "os"
+        "path/filepath"
+        "sort"
+        "strconv"
+        "time"
+
+        "sponsorgen/config"
+)
+
+// afdianLedgerFile is the name of the order ledger inside CacheDir
+const afdianLedgerFile = "afdian_orders.json"
+
+// AfdianLedger is the local store of every Afdian order seen so far
+type AfdianLedger struct {
+        UpdatedAt string                 `json:"updatedAt"`
+        Orders    map[string]AfdianOrder `json:"orders"` // keyed by out_trade_no
+}
+
+// fetchAfdianLedgerSponsors syncs new orders into the local ledger and
+// builds sponsors from it. Profiles are used for names and avatars.
+func fetchAfdianLedgerSponsors(ctx context.Context, client *http.Client, cfg config.Config, profiles []AfdianSponsor) ([]Sponsor, error) {
+        ledgerPath := filepath.Join(cfg.CacheDir, afdianLedgerFile)
+
+        ledger, err := loadAfdianLedger(ledgerPath)
+        if err != nil {
+                return nil, err
+        }
+
+        // Only page back until we reach orders we already know about
+        var known func(AfdianOrder) bool
+        if len(ledger.Orders) > 0 {
+                known = func(order AfdianOrder) bool {
+                        _, found := ledger.Orders[order.OutTradeNo]
+                        return found
+                }
+        }
+
+        orders, err := fetchAfdianOrdersUntil(ctx, client, cfg, known)
+        if err != nil {
+                return nil, fmt.Errorf("fetching orders: %w", err)
+        }
+
+        added := 0
+        for _, order := range orders {
+                if order.OutTradeNo == "" {
+                        continue
+                }
+                if _, found := ledger.Orders[order.OutTradeNo]; !found {
+                        added++
+                }
+                ledger.Orders[order.OutTradeNo] = order
+        }
+
+        if err := saveAfdianLedger(ledgerPath, ledger); err != nil {
+                return nil, err
+        }
+        log.Printf("Afdian order ledger has %d orders (%d new)", len(ledger.Orders), added)
+
+        ledgerOrders := make([]AfdianOrder, 0, len(ledger.Orders))
+        for _, order := range ledger.Orders {
+                ledgerOrders = append(ledgerOrders, order)
+        }
+
+        profilesByUser := make(map[string]AfdianSponsor)
+        for _, profile := range profiles {
+                profilesByUser[profile.User.UserID] = profile
+        }
+
+        // Sort users for a stable output order
+        ordersByUser := groupAfdianOrders(ledgerOrders)
+        userIDs := make([]string, 0, len(ordersByUser))
+        for userID := range ordersByUser {
+                userIDs = append(userIDs, userID)
+        }
+        sort.Strings(userIDs)
+
+        var sponsors []Sponsor
+        now := time.Now()
+        for _, userID := range userIDs {
+                profile, found := profilesByUser[userID]
+                if !found {
+                        profile.User.UserID = userID
+                }
+
+                if sponsor, ok := afdianSponsorFromLedger(cfg, profile, ordersByUser[userID], now); ok {
+                        sponsors = append(sponsors, sponsor)
+                }
+        }
+
+        return sponsors, nil
+}
+
+// afdianSponsorFromLedger computes a sponsor's lifetime total, last payment
+// and current recurring amount from their paid orders. It returns false for
+// sponsors that should not be shown.
+func afdianSponsorFromLedger(cfg config.Config, profile AfdianSponsor, orders []AfdianOrder, now time.Time) (Sponsor, bool) {
+        lifetime := 0.0
+        var first, last int64
+        for _, order := range orders {
+                amount, _ := strconv.ParseFloat(order.TotalAmount, 64)
+                lifetime += amount
+                if first == 0 || order.CreateTime < first {
+                        first = order.CreateTime
+                }
+                if order.CreateTime > last {
+                        last = order.CreateTime
+                }
+        }
+
+        name := profile.User.Name
+        if name == "" {
+                name = "Afdian Sponsor"
+        }
+
+        sponsor := Sponsor{
+                ID:             profile.User.UserID,
+                Name:           name,
+                Login:          name, // Use name as login since Afdian doesn't have a separate login field
+                AvatarURL:      profile.User.Avatar,
+                Link:           fmt.Sprintf("https://afdian.com/@%s", profile.User.UserID),
+                Platform:       "afdian",
+                CreatedAt:      time.Unix(first, 0).Format(time.RFC3339),
+                TierName:       profile.CurrentPlan.Name,
+                LifetimeAmount: lifetime,
+                LastPaymentAt:  time.Unix(last, 0).Format(time.RFC3339),
+                Months:         afdianPaidMonths(orders),
+                Status:         StatusActive,
+        }
+
+        latest, ok := latestAfdianPlanOrder(orders)
+        if !ok {
+                // Only custom-amount or product orders: a one-time sponsorship
+                if lifetime <= 0 || !includeOneTime(cfg, time.Unix(last, 0)) {
+                        return Sponsor{}, false
+                }
+                sponsor.OneTimeAmount = lifetime
+                sponsor.IsOneTime = true
+                return sponsor, true
+        }
+
+        months := afdianOrderMonths(latest)
+        amount, _ := strconv.ParseFloat(latest.TotalAmount, 64)
+        sponsor.MonthlyAmount = amount / float64(months)
+
+        // The latest plan order covers its number of months
+        if now.After(time.Unix(latest.CreateTime, 0).AddDate(0, months, 0)) {
+                if !cfg.IncludePastSponsors {
+                        return Sponsor{}, false
+                }
+                sponsor.Status = StatusPast
+        }
+
+        return sponsor, true
+}
+
+// loadAfdianLedger reads the order ledger, returning an empty one if it does not exist yet
+func loadAfdianLedger(path string) (AfdianLedger, error) {
+        ledger := AfdianLedger{Orders: map[string]AfdianOrder{}}
+
+        data, err := os.ReadFile(path)
+        if os.IsNotExist(err) {
+                return ledger, nil
+        }
+        if err != nil {
+                return ledger, fmt.Errorf("reading order ledger: %w", err)
+        }
+
+        if err := json.Unmarshal(data, &ledger); err != nil {
+                return ledger, fmt.Errorf("parsing order ledger: %w", err)
+        }
+        if ledger.Orders == nil {
+                ledger.Orders = map[string]AfdianOrder{}
+        }
+
+        return ledger, nil
+}
+
+// saveAfdianLedger writes the order ledger atomically
+func saveAfdianLedger(path string, ledger AfdianLedger) error {
+        ledger.UpdatedAt = time.Now().Format(time.RFC3339)
+
+        data, err := json.MarshalIndent(ledger, "", "  ")
+        if err != nil {
+                return fmt.Errorf("encoding order ledger: %w", err)
+        }
+
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+                return fmt.Errorf("creating ledger directory: %w", err)
+        }
+
+        tmpPath := path + ".tmp"
+        if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+                return fmt.Errorf("writing order ledger: %w", err)
+        }
+
+        return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,255 @@
+package sponsors
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "io"
+        "net/http"
+        "strconv"
+        "strings"
+        "time"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(buyMeACoffeeProvider{})
+}
+
+// buyMeACoffeeProvider fetches members and supporters from Buy Me a Coffee
+type buyMeACoffeeProvider struct{}
+
+func (buyMeACoffeeProvider) Name() string { return "buymeacoffee" }
+
+func (buyMeACoffeeProvider) Enabled(cfg config.Config) bool {
+        return cfg.BuyMeACoffeeToken != ""
+}
+
+func (buyMeACoffeeProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchBuyMeACoffeeSponsors(ctx, cfg)
+}
+
+// buyMeACoffeeTimeLayout is the timestamp format used by the API
+const buyMeACoffeeTimeLayout = "2006-01-02 15:04:05"
+
+// BuyMeACoffeeNumber decodes amounts the API returns either as numbers or strings
+type BuyMeACoffeeNumber float64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *BuyMeACoffeeNumber) UnmarshalJSON(data []byte) error {
+        value := strings.Trim(string(data), `"`)
+        if value == "" || value == "null" {
+                *n = 0
+                return nil
+        }
+        f, err := strconv.ParseFloat(value, 64)
+        if err != nil {
+                return err
+        }
+        *n = BuyMeACoffeeNumber(f)
+        return nil
+}
+
+// BuyMeACoffeePage represents a page of a paginated API response
+type BuyMeACoffeePage struct {
+        CurrentPage int             `json:"current_page"`
+        LastPage    int             `json:"last_page"`
+        Data        json.RawMessage `json:"data"`
+}
+
+// BuyMeACoffeeSubscription represents a membership
+type BuyMeACoffeeSubscription struct {
+        ID                  BuyMeACoffeeNumber `json:"subscription_id"`
+        CoffeePrice         BuyMeACoffeeNumber `json:"subscription_coffee_price"`
+        CoffeeNum           BuyMeACoffeeNumber `json:"subscription_coffee_num"`
+        Currency            string             `json:"subscription_currency"`
+        CreatedOn           string             `json:"subscription_created_on"`
+        DurationType        string             `json:"subscription_duration_type"` // month or year
+        IsCancelled         *bool              `json:"subscription_is_cancelled"`
+        Hidden              bool               `json:"subscription_hidden"`
+        PayerName           string             `json:"payer_name"`
+        MembershipLevelID   BuyMeACoffeeNumber `json:"membership_level_id"`
+        MembershipLevelName string             `json:"membership_level_name"`
+}
+
+// BuyMeACoffeeSupporter represents a one-time support
+type BuyMeACoffeeSupporter struct {
+        ID            BuyMeACoffeeNumber `json:"support_id"`
+        Coffees       BuyMeACoffeeNumber `json:"support_coffees"`
+        CoffeePrice   BuyMeACoffeeNumber `json:"support_coffee_price"`
+        Currency      string             `json:"support_currency"`
+        Visibility    BuyMeACoffeeNumber `json:"support_visibility"` // 1 for public
+        CreatedOn     string             `json:"support_created_on"`
+        SupporterName string             `json:"supporter_name"`
+        PayerName     string             `json:"payer_name"`
+}
+
+// FetchBuyMeACoffeeSponsors fetches active members (recurring) and
+// supporters (one-time) from Buy Me a Coffee
+func FetchBuyMeACoffeeSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        sponsors := []Sponsor{}
+
+        client := newHTTPClient(cfg, 10*time.Second)
+
+        link := fmt.Sprintf("https://buymeacoffee.com/%s", cfg.BuyMeACoffeeSlug)
+
+        // Members
+        err := fetchBuyMeACoffeePages(ctx, client, cfg, "subscriptions?status=active", func(data json.RawMessage) error {
+                var subscriptions []BuyMeACoffeeSubscription
+                if err := json.Unmarshal(data, &subscriptions); err != nil {
+                        return err
+                }
+
+                for _, subscription := range subscriptions {
+                        if subscription.IsCancelled != nil && *subscription.IsCancelled {
+                                continue
+                        }
+
+                        name, ok := buyMeACoffeeName(cfg, subscription.PayerName, !subscription.Hidden)
+                        if !ok {
+                                continue
+                        }
+
+                        monthlyAmount := float64(subscription.CoffeePrice) * float64(subscription.CoffeeNum)
+                        if subscription.DurationType == "year" {
+                                monthlyAmount /= 12
+                        }
+
+                        tierName := subscription.MembershipLevelName
+                        if tierName == "" && subscription.MembershipLevelID > 0 {
+                                tierName = fmt.Sprintf("Level %d", int(subscription.MembershipLevelID))
+                        }
+
+                        sponsors = append(sponsors, Sponsor{
+                                ID:            fmt.Sprintf("bmc-subscription-%d", int64(subscription.ID)),
+                                Name:          name,
+                                Login:         name,
+                                Link:          link,
+                                Platform:      "buymeacoffee",
+                                MonthlyAmount: monthlyAmount,
+                                CreatedAt:     buyMeACoffeeTime(subscription.CreatedOn),
+                                TierName:      tierName,
+                        })
+                }
+                return nil
+        })
+        if err != nil {
+                return sponsors, fmt.Errorf("members: %w", err)
+        }
+
+        // One-time supporters follow the one-time policy
+        if cfg.OneTimePolicy == config.OneTimeExclude {
+                return sponsors, nil
+        }
+
+        err = fetchBuyMeACoffeePages(ctx, client, cfg, "supporters", func(data json.RawMessage) error {
+                var supporters []BuyMeACoffeeSupporter
+                if err := json.Unmarshal(data, &supporters); err != nil {
+                        return err
+                }
+
+                for _, supporter := range supporters {
+                        createdAt := buyMeACoffeeTime(supporter.CreatedOn)
+                        if !includeOneTime(cfg, parseTime(createdAt)) {
+                                continue
+                        }
+
+                        supporterName := supporter.SupporterName
+                        if supporterName == "" {
+                                supporterName = supporter.PayerName
+                        }
+                        name, ok := buyMeACoffeeName(cfg, supporterName, supporter.Visibility == 1)
+                        if !ok {
+                                continue
+                        }
+
+                        sponsors = append(sponsors, Sponsor{
+                                ID:            fmt.Sprintf("bmc-support-%d", int64(supporter.ID)),
+                                Name:          name,
+                                Login:         name,
+                                Link:          link,
+                                Platform:      "buymeacoffee",
+                                OneTimeAmount: float64(supporter.CoffeePrice) * float64(supporter.Coffees),
+                                CreatedAt:     createdAt,
+                                IsOneTime:     true,
+                        })
+                }
+                return nil
+        })
+        if err != nil {
+                return sponsors, fmt.Errorf("supporters: %w", err)
+        }
+
+        return sponsors, nil
+}
+
+// fetchBuyMeACoffeePages requests every page of an endpoint and passes the
+// data of each page to handle
+func fetchBuyMeACoffeePages(ctx context.Context, client *http.Client, cfg config.Config, endpoint string, handle func(json.RawMessage) error) error {
+        separator := "?"
+        if strings.Contains(endpoint, "?") {
+                separator = "&"
+        }
+
+        for page := 1; ; page++ {
+                url := fmt.Sprintf("%s/%s%spage=%d", cfg.BuyMeACoffeeAPIURL, endpoint, separator, page)
+
+                req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+                if err != nil {
+                        return fmt.Errorf("failed to create Buy Me a Coffee API request: %w", err)
+                }
+
+                req.Header.Set("Authorization", "Bearer "+cfg.BuyMeACoffeeToken)
+
+                resp, err := client.Do(req)
+                if err != nil {
+                        return fmt.Errorf("failed to execute Buy Me a Coffee API request: %w", err)
+                }
+
+                if resp.StatusCode != http.StatusOK {
+                        body, _ := io.ReadAll(resp.Body)
+                        resp.Body.Close()
+                        return fmt.Errorf("Buy Me a Coffee API request failed with status %d: %s", resp.StatusCode, string(body))
+                }
+
+                var response BuyMeACoffeePage
+                err = json.NewDecoder(resp.Body).Decode(&response)
+                resp.Body.Close()
+                if err != nil {
+                        return fmt.Errorf("failed to decode Buy Me a Coffee API response: %w", err)
+                }
+
+                if err := handle(response.Data); err != nil {
+                        return fmt.Errorf("failed to decode Buy Me a Coffee API data: %w", err)
+                }
+
+                if response.LastPage <= page {
+                        return nil
+                }
+        }
+}
+
+// buyMeACoffeeName returns the display name of a supporter, honoring their
+// visibility choice. It returns false if the supporter should be skipped.
+func buyMeACoffeeName(cfg config.Config, name string, public bool) (string, bool) {
+        if !public {
+                if !cfg.IncludePrivate {
+                        return "", false
+                }
+                return "Anonymous Supporter", true
+        }
+        if name == "" {
+                name = "Someone"
+        }
+        return name, true
+}
+
+// buyMeACoffeeTime converts an API timestamp to RFC 3339
+func buyMeACoffeeTime(value string) string {
+        t, err := time.Parse(buyMeACoffeeTimeLayout, value)
+        if err != nil {
+                return value
+        }
+        return t.Format(time.RFC3339)
+}
@@ -0,0 +1,144 @@
+package sponsors
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "net/url"
+        "os/exec"
+        "path/filepath"
+        "strings"
+        "time"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(commandProvider{})
+}
+
+// commandProvider runs an external command that prints sponsors as JSON
+type commandProvider struct{}
+
+func (commandProvider) Name() string { return "command" }
+
+func (commandProvider) Enabled(cfg config.Config) bool {
+        return strings.TrimSpace(cfg.SponsorsCommand) != ""
+}
+
+func (commandProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchCommandSponsors(ctx, cfg)
+}
+
+// maxCommandStderr limits how much stderr output is included in errors
+const maxCommandStderr = 2048
+
+// FetchCommandSponsors runs SponsorsCommand and reads a JSON array of
+// sponsors from its stdout. The command is split on whitespace and run
+// without a shell. A non-zero exit status is reported together with the
+// command's stderr.
+func FetchCommandSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        args := strings.Fields(cfg.SponsorsCommand)
+        if len(args) == 0 {
+                return nil, fmt.Errorf("no command configured")
+        }
+
+        timeout := time.Duration(cfg.SponsorsCommandTimeout) * time.Second
+        if timeout <= 0 {
+                timeout = 30 * time.Second
+        }
+        ctx, cancel := context.WithTimeout(ctx, timeout)
+        defer cancel()
+
+        var stdout, stderr bytes.Buffer
+        cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+        cmd.Stdout = &stdout
+        cmd.Stderr = &stderr
+
+        if err := cmd.Run(); err != nil {
+                if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+                        return nil, fmt.Errorf("command %s timed out after %s", args[0], timeout)
+                }
+                return nil, fmt.Errorf("command %s failed: %w: %s", args[0], err, truncateOutput(stderr.String()))
+        }
+
+        decoder := json.NewDecoder(&stdout)
+        decoder.DisallowUnknownFields()
+
+        var records []Sponsor
+        if err := decoder.Decode(&records); err != nil {
+                return nil, fmt.Errorf("command %s produced invalid JSON: %w", args[0], err)
+        }
+
+        for i := range records {
+                if err := validateCommandSponsor(&records[i]); err != nil {
+                        return nil, fmt.Errorf("command %s record %d: %w", args[0], i+1, err)
+                }
+        }
+
+        return records, nil
+}
+
+// validateCommandSponsor checks a sponsor record produced by a command and
+// fills in defaults for optional fields
+func validateCommandSponsor(sponsor *Sponsor) error {
+        sponsor.Name = strings.TrimSpace(sponsor.Name)
+        sponsor.Login = strings.TrimSpace(sponsor.Login)
+
+        if sponsor.Name == "" && sponsor.Login == "" {
+                return fmt.Errorf("name or login is required")
+        }
+        if sponsor.Name == "" {
+                sponsor.Name = sponsor.Login
+        }
+        if sponsor.Login == "" {
+                sponsor.Login = sponsor.Name
+        }
+        if sponsor.ID == "" {
+                sponsor.ID = "command-" + strings.ToLower(sponsor.Login)
+        }
+        if sponsor.Platform == "" {
+                sponsor.Platform = "command"
+        }
+
+        if sponsor.MonthlyAmount < 0 || sponsor.OneTimeAmount < 0 || sponsor.LifetimeAmount < 0 {
+                return fmt.Errorf("amounts must not be negative")
+        }
+
+        if sponsor.CreatedAt != "" {
+                if _, err := time.Parse(time.RFC3339, sponsor.CreatedAt); err != nil {
+                        return fmt.Errorf("createdAt is not an RFC 3339 timestamp: %q", sponsor.CreatedAt)
+                }
+        }
+        if sponsor.LastPaymentAt != "" {
+                if _, err := time.Parse(time.RFC3339, sponsor.LastPaymentAt); err != nil {
+                        return fmt.Errorf("lastPaymentAt is not an RFC 3339 timestamp: %q", sponsor.LastPaymentAt)
+                }
+        }
+
+        switch sponsor.Status {
+        case "", StatusActive, StatusPast, StatusDeclined, StatusLapsed:
+        default:
+                return fmt.Errorf("unknown status %q", sponsor.Status)
+        }
+
+        if sponsor.AvatarURL != "" && !strings.HasPrefix(sponsor.AvatarURL, "data:") && !filepath.IsAbs(sponsor.AvatarURL) {
+                parsed, err := url.Parse(sponsor.AvatarURL)
+                if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+                        return fmt.Errorf("avatarUrl must be an http(s) URL, a data URI or an absolute path: %q", sponsor.AvatarURL)
+                }
+        }
+
+        return nil
+}
+
+// truncateOutput trims command output for inclusion in error messages
+func truncateOutput(output string) string {
+        output = strings.TrimSpace(output)
+        if len(output) > maxCommandStderr {
+                output = output[:maxCommandStderr] + "..."
+        }
+        return output
+}
@@ -2,92 +2,226 @@ package sponsors
 
 import (
         "bytes"
+        "context"
         "encoding/json"
         "fmt"
         "io"
         "net/http"
+        "strings"
         "time"
 
         "sponsorgen/config"
 )
 
+func init() {
+        Register(githubProvider{})
+}
+
+// githubProvider fetches sponsors from GitHub Sponsors
+type githubProvider struct{}
+
+func (githubProvider) Name() string { return "github" }
+
+func (githubProvider) Enabled(cfg config.Config) bool {
+        return cfg.GitHubToken != "" && (cfg.GitHubLogin != "" || len(cfg.GitHubOrgs) > 0)
+}
+
+func (githubProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        sponsors, err := FetchGitHubSponsors(ctx, cfg)
+        if err != nil || cfg.GitHubExportDir == "" {
+                return sponsors, err
+        }
+        return enrichFromGitHubExport(cfg, sponsors), nil
+}
+
 // GitHubSponsorResponse represents the GitHub GraphQL API response for sponsors
 type GitHubSponsorResponse struct {
         Data struct {
-                User struct {
-                        SponsorshipsAsMaintainer struct {
-                                Nodes []struct {
-                                        CreatedAt  string `json:"createdAt"`
-                                        IsOneTime  bool   `json:"isOneTimePayment"`
-                                        TierName   string `json:"tier"`
-                                        Sponsor    struct {
-                                                Login     string `json:"login"`
-                                                Name      string `json:"name"`
-                                                AvatarURL string `json:"avatarUrl"`
-                                                URL       string `json:"url"`
-                                                ID        string `json:"id"`
-                                        } `json:"sponsorEntity"`
-                                        TotalAmountDonated struct {
-                                                Currency string `json:"currency"`
-                                                Value    int    `json:"value"`
-                                        } `json:"totalDonated"`
-                                        TierAmount struct {
-                                                Currency string  `json:"currency"`
-                                                Value    float64 `json:"value"`
-                                        } `json:"tier"`
-                                } `json:"nodes"`
+                RepositoryOwner *struct {
+                        Typename           string `json:"__typename"`
+                        HasSponsorsListing bool   `json:"hasSponsorsListing"`
+                        GitHubSponsorable
+                } `json:"repositoryOwner"`
+        } `json:"data"`
+        GitHubGraphQLErrors
+}
+
+// GitHubActivityResponse represents the GitHub GraphQL API response for sponsors activities
+type GitHubActivityResponse struct {
+        Data struct {
+                RepositoryOwner *struct {
+                        SponsorsActivities struct {
+                                Nodes    []GitHubSponsorsActivity `json:"nodes"`
                                 PageInfo struct {
                                         HasNextPage bool   `json:"hasNextPage"`
                                         EndCursor   string `json:"endCursor"`
                                 } `json:"pageInfo"`
-                        } `json:"sponsorshipsAsMaintainer"`
-                } `json:"user"`
+                        } `json:"sponsorsActivities"`
+                } `json:"repositoryOwner"`
         } `json:"data"`
+        GitHubGraphQLErrors
+}
+
+// GitHubGraphQLErrors holds the errors returned by the GitHub GraphQL API
+type GitHubGraphQLErrors struct {
         Errors []struct {
                 Message string `json:"message"`
                 Type    string `json:"type"`
         } `json:"errors"`
 }
 
-// FetchGitHubSponsors fetches sponsors from GitHub using the GraphQL API
-func FetchGitHubSponsors(cfg config.Config) ([]Sponsor, error) {
+func (e GitHubGraphQLErrors) firstError() error {
+        if len(e.Errors) == 0 {
+                return nil
+        }
+        return fmt.Errorf("GitHub GraphQL API error: %s", e.Errors[0].Message)
+}
+
+// GitHubSponsorsActivity represents an entry of a sponsorable's sponsors activity log
+type GitHubSponsorsActivity struct {
+        Action    string              `json:"action"`
+        Timestamp string              `json:"timestamp"`
+        Sponsor   GitHubSponsorEntity `json:"sponsor"`
+        Tier      *GitHubSponsorsTier `json:"sponsorsTier"`
+}
+
+// GitHubSponsorable represents a GitHub user or organization that can be sponsored
+type GitHubSponsorable struct {
+        SponsorshipsAsMaintainer struct {
+                Nodes    []GitHubSponsorship `json:"nodes"`
+                PageInfo struct {
+                        HasNextPage bool   `json:"hasNextPage"`
+                        EndCursor   string `json:"endCursor"`
+                } `json:"pageInfo"`
+        } `json:"sponsorshipsAsMaintainer"`
+}
+
+// GitHubSponsorship represents a single sponsorship of a sponsorable account
+type GitHubSponsorship struct {
+        CreatedAt string              `json:"createdAt"`
+        IsActive  bool                `json:"isActive"`
+        IsOneTime bool                `json:"isOneTimePayment"`
+        Tier      *GitHubSponsorsTier `json:"tier"`
+        Sponsor   GitHubSponsorEntity `json:"sponsorEntity"`
+}
+
+// GitHubSponsorEntity represents the user or organization behind a sponsorship
+type GitHubSponsorEntity struct {
+        ID        string `json:"id"`
+        Login     string `json:"login"`
+        Name      string `json:"name"`
+        AvatarURL string `json:"avatarUrl"`
+        URL       string `json:"url"`
+}
+
+// GitHubSponsorsTier represents a GitHub Sponsors tier. Custom amounts are
+// reported as their own tier with IsCustomAmount set.
+type GitHubSponsorsTier struct {
+        ID                  string `json:"id"`
+        Name                string `json:"name"`
+        MonthlyPriceInCents int    `json:"monthlyPriceInCents"`
+        IsOneTime           bool   `json:"isOneTime"`
+        IsCustomAmount      bool   `json:"isCustomAmount"`
+}
+
+// FetchGitHubSponsors fetches sponsors from GitHub using the GraphQL API.
+// Sponsors of the configured login, which may be a user or an organization,
+// and of every organization in GitHubOrgs are returned together.
+func FetchGitHubSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
         sponsors := []Sponsor{}
-        
+
+        client := newHTTPClient(cfg, 10*time.Second)
+
+        if cfg.GitHubLogin != "" {
+                loginSponsors, err := fetchGitHubAccountSponsors(ctx, client, cfg, cfg.GitHubLogin)
+                if err != nil {
+                        return sponsors, err
+                }
+                sponsors = append(sponsors, loginSponsors...)
+        }
+
+        // Also fetch from orgs if specified
+        for _, org := range cfg.GitHubOrgs {
+                org = strings.TrimSpace(org)
+                if org == "" {
+                        continue
+                }
+
+                orgSponsors, err := fetchGitHubAccountSponsors(ctx, client, cfg, org)
+                if err != nil {
+                        return sponsors, fmt.Errorf("organization %s: %w", org, err)
+                }
+
+                sponsors = append(sponsors, orgSponsors...)
+        }
+
+        return sponsors, nil
+}
+
+// fetchGitHubAccountSponsors fetches the active sponsors of a single
+// sponsorable account, followed by its past sponsors if enabled
+func fetchGitHubAccountSponsors(ctx context.Context, client *http.Client, cfg config.Config, login string) ([]Sponsor, error) {
+        sponsors, err := fetchGitHubSponsorships(ctx, client, cfg, login)
+        if err != nil {
+                return sponsors, err
+        }
+
+        if cfg.IncludePastSponsors {
+                pastSponsors, err := fetchGitHubPastSponsors(ctx, client, cfg, login, sponsors)
+                if err != nil {
+                        return sponsors, fmt.Errorf("past sponsors: %w", err)
+                }
+                sponsors = append(sponsors, pastSponsors...)
+        }
+
+        return sponsors, nil
+}
+
+// fetchGitHubSponsorships pages through the sponsorships of a sponsorable
+// account. The account is looked up through repositoryOwner so that both
+// users and organizations are supported.
+func fetchGitHubSponsorships(ctx context.Context, client *http.Client, cfg config.Config, login string) ([]Sponsor, error) {
+        sponsors := []Sponsor{}
+
         query := `
         query($login: String!, $cursor: String) {
-                user(login: $login) {
-                        sponsorshipsAsMaintainer(first: 100, after: $cursor, includePrivate: %s) {
-                                nodes {
-                                        createdAt
-                                        isOneTimePayment
-                                        tier
-                                        sponsorEntity {
-                                                ... on User {
-                                                        id
-                                                        login
-                                                        name
-                                                        avatarUrl
-                                                        url
+                repositoryOwner(login: $login) {
+                        __typename
+                        ... on Sponsorable {
+                                hasSponsorsListing
+                                sponsorshipsAsMaintainer(first: 100, after: $cursor, includePrivate: %s, activeOnly: %s) {
+                                        nodes {
+                                                createdAt
+                                                isActive
+                                                isOneTimePayment
+                                                sponsorEntity {
+                                                        ... on User {
+                                                                id
+                                                                login
+                                                                name
+                                                                avatarUrl
+                                                                url
+                                                        }
+                                                        ... on Organization {
+                                                                id
+                                                                login
+                                                                name
+                                                                avatarUrl
+                                                                url
+                                                        }
                                                 }
-                                                ... on Organization {
+                                                tier {
                                                         id
-                                                        login
                                                         name
-                                                        avatarUrl
-                                                        url
+                                                        monthlyPriceInCents
+                                                        isOneTime
+                                                        isCustomAmount
                                                 }
                                         }
-                                        totalDonated {
-                                                currency
-                                                value
+                                        pageInfo {
+                                                hasNextPage
+                                                endCursor
                                         }
-                                        tier {
-                                                monthlyPriceInDollars
-                                        }
-                                }
-                                pageInfo {
-                                        hasNextPage
-                                        endCursor
                                 }
                         }
                 }
@@ -99,114 +233,260 @@ func FetchGitHubSponsors(cfg config.Config) ([]Sponsor, error) {
                 includePrivate = "true"
         }
         
-        query = fmt.Sprintf(query, includePrivate)
-        
-        client := &http.Client{
-                Timeout: 10 * time.Second,
+        // Finished one-time sponsorships are only returned with activeOnly
+        // disabled, which also returns inactive recurring ones
+        activeOnly := "true"
+        if cfg.OneTimePolicy != config.OneTimeExclude {
+                activeOnly = "false"
         }
         
+        query = fmt.Sprintf(query, includePrivate, activeOnly)
+        
         hasNextPage := true
         cursor := ""
         
         for hasNextPage {
                 variables := map[string]interface{}{
-                        "login":  cfg.GitHubLogin,
+                        "login":  login,
                         "cursor": cursor,
                 }
                 
-                requestBody, err := json.Marshal(map[string]interface{}{
-                        "query":     query,
-                        "variables": variables,
-                })
-                
-                if err != nil {
-                        return sponsors, fmt.Errorf("failed to marshal GitHub GraphQL request: %w", err)
-                }
-                
-                req, err := http.NewRequest("POST", "https://api.github.com/graphql", bytes.NewBuffer(requestBody))
-                if err != nil {
-                        return sponsors, fmt.Errorf("failed to create GitHub GraphQL request: %w", err)
-                }
-                
-                req.Header.Set("Authorization", "bearer "+cfg.GitHubToken)
-                req.Header.Set("Content-Type", "application/json")
-                
-                resp, err := client.Do(req)
-                if err != nil {
-                        return sponsors, fmt.Errorf("failed to execute GitHub GraphQL request: %w", err)
+                var response GitHubSponsorResponse
+                if err := executeGitHubQuery(ctx, client, cfg, query, variables, &response); err != nil {
+                        return sponsors, err
                 }
-                defer resp.Body.Close()
-                
-                if resp.StatusCode != http.StatusOK {
-                        body, _ := io.ReadAll(resp.Body)
-                        return sponsors, fmt.Errorf("GitHub GraphQL request failed with status %d: %s", resp.StatusCode, string(body))
+
+                owner := response.Data.RepositoryOwner
+                if owner == nil {
+                        return sponsors, fmt.Errorf("GitHub account %s not found", login)
                 }
-                
-                var response GitHubSponsorResponse
-                if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-                        return sponsors, fmt.Errorf("failed to decode GitHub GraphQL response: %w", err)
+
+                // Only users and organizations implement Sponsorable
+                accountType := strings.ToLower(owner.Typename)
+                if accountType != "user" && accountType != "organization" {
+                        return sponsors, fmt.Errorf("GitHub account %s is a %s and cannot be sponsored", login, owner.Typename)
                 }
-                
-                if len(response.Errors) > 0 {
-                        return sponsors, fmt.Errorf("GitHub GraphQL API error: %s", response.Errors[0].Message)
+
+                if !owner.HasSponsorsListing {
+                        return sponsors, fmt.Errorf("GitHub %s %s has no GitHub Sponsors profile", accountType, login)
                 }
+
+                sponsorable := owner.GitHubSponsorable
                 
                 // Process sponsors from this page
-                for _, node := range response.Data.User.SponsorshipsAsMaintainer.Nodes {
-                        // Skip one-time payments
-                        if node.IsOneTime {
-                                continue
-                        }
-                        
+                for _, node := range sponsorable.SponsorshipsAsMaintainer.Nodes {
                         // Skip sponsors with no entity (deleted accounts etc.)
                         if node.Sponsor.Login == "" {
                                 continue
                         }
                         
-                        sponsor := Sponsor{
-                                ID:            node.Sponsor.ID,
-                                Name:          node.Sponsor.Name,
-                                Login:         node.Sponsor.Login,
-                                AvatarURL:     node.Sponsor.AvatarURL,
-                                Link:          node.Sponsor.URL,
-                                Platform:      "github",
-                                MonthlyAmount: node.TierAmount.Value,
-                                CreatedAt:     node.CreatedAt,
-                                TierName:      node.TierName,
+                        sponsor := githubSponsorFromNode(node)
+
+                        // One-time payments follow the one-time policy, recurring
+                        // sponsorships are only shown while active
+                        if sponsor.IsOneTime {
+                                if !includeOneTime(cfg, parseTime(node.CreatedAt)) {
+                                        continue
+                                }
+                        } else if !node.IsActive {
+                                continue
                         }
-                        
+
+                        sponsor.Status = StatusActive
                         sponsors = append(sponsors, sponsor)
                 }
                 
                 // Check if there are more pages
-                hasNextPage = response.Data.User.SponsorshipsAsMaintainer.PageInfo.HasNextPage
+                hasNextPage = sponsorable.SponsorshipsAsMaintainer.PageInfo.HasNextPage
                 if hasNextPage {
-                        cursor = response.Data.User.SponsorshipsAsMaintainer.PageInfo.EndCursor
+                        cursor = sponsorable.SponsorshipsAsMaintainer.PageInfo.EndCursor
                 }
         }
         
-        // Also fetch from orgs if specified
-        for _, org := range cfg.GitHubOrgs {
-                orgSponsors, err := fetchGitHubOrgSponsors(org, cfg)
-                if err != nil {
-                        // Log error but continue
-                        fmt.Printf("Error fetching sponsors for org %s: %v\n", org, err)
-                        continue
+        return sponsors, nil
+}
+
+// fetchGitHubPastSponsors walks the sponsors activity log of an account and
+// returns everyone who sponsored it in the past but is not in active
+func fetchGitHubPastSponsors(ctx context.Context, client *http.Client, cfg config.Config, login string, active []Sponsor) ([]Sponsor, error) {
+        query := `
+        query($login: String!, $cursor: String) {
+                repositoryOwner(login: $login) {
+                        ... on Sponsorable {
+                                sponsorsActivities(first: 100, after: $cursor, period: ALL, actions: [NEW_SPONSORSHIP, CANCELLED_SPONSORSHIP]) {
+                                        nodes {
+                                                action
+                                                timestamp
+                                                sponsor {
+                                                        ... on User {
+                                                                id
+                                                                login
+                                                                name
+                                                                avatarUrl
+                                                                url
+                                                        }
+                                                        ... on Organization {
+                                                                id
+                                                                login
+                                                                name
+                                                                avatarUrl
+                                                                url
+                                                        }
+                                                }
+                                                sponsorsTier {
+                                                        id
+                                                        name
+                                                        monthlyPriceInCents
+                                                        isOneTime
+                                                        isCustomAmount
+                                                }
+                                        }
+                                        pageInfo {
+                                                hasNextPage
+                                                endCursor
+                                        }
+                                }
+                        }
                 }
-                
-                sponsors = append(sponsors, orgSponsors...)
         }
-        
-        return sponsors, nil
+        `
+
+        activeLogins := make(map[string]bool)
+        for _, sponsor := range active {
+                activeLogins[strings.ToLower(sponsor.Login)] = true
+        }
+
+        // Keep the most recent sponsorship of every former sponsor
+        past := make(map[string]Sponsor)
+        var order []string
+
+        hasNextPage := true
+        var cursor interface{}
+
+        for hasNextPage {
+                variables := map[string]interface{}{
+                        "login":  login,
+                        "cursor": cursor,
+                }
+
+                var response GitHubActivityResponse
+                if err := executeGitHubQuery(ctx, client, cfg, query, variables, &response); err != nil {
+                        return nil, err
+                }
+
+                owner := response.Data.RepositoryOwner
+                if owner == nil {
+                        return nil, fmt.Errorf("GitHub account %s not found", login)
+                }
+
+                for _, activity := range owner.SponsorsActivities.Nodes {
+                        if activity.Action != "NEW_SPONSORSHIP" || activity.Sponsor.Login == "" {
+                                continue
+                        }
+
+                        // One-time payments are governed by the one-time policy
+                        if activity.Tier != nil && activity.Tier.IsOneTime {
+                                continue
+                        }
+
+                        key := strings.ToLower(activity.Sponsor.Login)
+                        if activeLogins[key] {
+                                continue
+                        }
+
+                        if existing, found := past[key]; found && existing.CreatedAt >= activity.Timestamp {
+                                continue
+                        } else if !found {
+                                order = append(order, key)
+                        }
+
+                        sponsor := githubSponsorFromNode(GitHubSponsorship{
+                                CreatedAt: activity.Timestamp,
+                                Tier:      activity.Tier,
+                                Sponsor:   activity.Sponsor,
+                        })
+                        sponsor.Status = StatusPast
+                        past[key] = sponsor
+                }
+
+                hasNextPage = owner.SponsorsActivities.PageInfo.HasNextPage
+                cursor = owner.SponsorsActivities.PageInfo.EndCursor
+        }
+
+        pastSponsors := make([]Sponsor, 0, len(order))
+        for _, key := range order {
+                pastSponsors = append(pastSponsors, past[key])
+        }
+
+        return pastSponsors, nil
 }
 
-// fetchGitHubOrgSponsors fetches sponsors for a GitHub organization
-func fetchGitHubOrgSponsors(orgLogin string, cfg config.Config) ([]Sponsor, error) {
-        // Similar implementation to FetchGitHubSponsors but for organizations
-        // This is a simplified version as the full implementation would be quite lengthy
-        
-        // For the sake of this example, we'll just return an empty slice
-        // In a real implementation, you would use a similar GraphQL query adjusted for organizations
-        
-        return []Sponsor{}, nil
+// executeGitHubQuery runs a GraphQL query against the GitHub API and decodes
+// the result into response
+func executeGitHubQuery(ctx context.Context, client *http.Client, cfg config.Config, query string, variables map[string]interface{}, response interface{ firstError() error }) error {
+        requestBody, err := json.Marshal(map[string]interface{}{
+                "query":     query,
+                "variables": variables,
+        })
+        if err != nil {
+                return fmt.Errorf("failed to marshal GitHub GraphQL request: %w", err)
+        }
+
+        req, err := http.NewRequestWithContext(ctx, "POST", cfg.GitHubAPIURL, bytes.NewBuffer(requestBody))
+        if err != nil {
+                return fmt.Errorf("failed to create GitHub GraphQL request: %w", err)
+        }
+
+        // Queries do not change anything and can be retried
+        req = markRetryable(req)
+
+        req.Header.Set("Authorization", "bearer "+cfg.GitHubToken)
+        req.Header.Set("Content-Type", "application/json")
+
+        resp, err := client.Do(req)
+        if err != nil {
+                return fmt.Errorf("failed to execute GitHub GraphQL request: %w", err)
+        }
+        defer resp.Body.Close()
+
+        if resp.StatusCode != http.StatusOK {
+                body, _ := io.ReadAll(resp.Body)
+                return fmt.Errorf("GitHub GraphQL request failed with status %d: %s", resp.StatusCode, string(body))
+        }
+
+        if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+                return fmt.Errorf("failed to decode GitHub GraphQL response: %w", err)
+        }
+
+        return response.firstError()
+}
+
+// githubSponsorFromNode converts a GitHub sponsorship into a Sponsor
+func githubSponsorFromNode(node GitHubSponsorship) Sponsor {
+        sponsor := Sponsor{
+                ID:        node.Sponsor.ID,
+                Name:      node.Sponsor.Name,
+                Login:     node.Sponsor.Login,
+                AvatarURL: node.Sponsor.AvatarURL,
+                Link:      node.Sponsor.URL,
+                Platform:  "github",
+                CreatedAt: node.CreatedAt,
+                IsOneTime: node.IsOneTime,
+        }
+
+        if node.Tier != nil {
+                sponsor.TierID = node.Tier.ID
+                sponsor.TierName = node.Tier.Name
+                sponsor.MonthlyAmount = float64(node.Tier.MonthlyPriceInCents) / 100.0
+                sponsor.IsCustomAmount = node.Tier.IsCustomAmount
+                sponsor.IsOneTime = node.IsOneTime || node.Tier.IsOneTime
+        }
+
+        // One-time amounts are a total, not a monthly contribution
+        if sponsor.IsOneTime {
+                sponsor.OneTimeAmount = sponsor.MonthlyAmount
+                sponsor.MonthlyAmount = 0
+        }
+
+        return sponsor
 }
@@ -0,0 +1,435 @@
+package sponsors
+
+import (
+        "context"
+        "encoding/csv"
+        "encoding/json"
+        "fmt"
+        "io"
+        "log"
+        "os"
+        "path/filepath"
+        "sort"
+        "strconv"
+        "strings"
+        "time"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(githubExportProvider{})
+}
+
+// githubExportProvider reads GitHub Sponsors exports from a directory. It
+// stands in for the live GitHub provider when no token is configured; with
+// a token the exports only enrich the live sponsors.
+type githubExportProvider struct{}
+
+func (githubExportProvider) Name() string { return "github_export" }
+
+func (githubExportProvider) Enabled(cfg config.Config) bool {
+        return cfg.GitHubExportDir != "" && cfg.GitHubToken == ""
+}
+
+func (githubExportProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return LoadGitHubExportSponsors(ctx, cfg)
+}
+
+// githubExportRow is a single transaction of an export, keyed by normalized
+// column name
+type githubExportRow map[string]string
+
+// get returns the value of the first of the given columns that is present
+func (r githubExportRow) get(columns ...string) string {
+        for _, column := range columns {
+                if value, ok := r[column]; ok && value != "" {
+                        return value
+                }
+        }
+        return ""
+}
+
+// githubExportDateLayouts are the date formats seen in exports
+var githubExportDateLayouts = []string{
+        time.RFC3339,
+        "2006-01-02 15:04:05 -0700",
+        "2006-01-02 15:04:05 MST",
+        "2006-01-02 15:04:05",
+        "2006-01-02",
+        "01/02/2006",
+}
+
+// LoadGitHubExportSponsors reads every CSV and JSON export in GitHubExportDir
+// and builds one sponsor per sponsor handle with its lifetime total. The
+// directory is scanned again on every refresh, and transactions that appear
+// in several exports are only counted once.
+func LoadGitHubExportSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        rows, err := readGitHubExportDir(cfg.GitHubExportDir)
+        if err != nil {
+                return nil, err
+        }
+
+        sponsors := []Sponsor{}
+
+        for _, sponsor := range githubSponsorsFromExport(cfg, rows, time.Now()) {
+                if sponsor.IsOneTime && !includeOneTime(cfg, parseTime(sponsor.LastPaymentAt)) {
+                        continue
+                }
+                if sponsor.IsPast() && !cfg.IncludePastSponsors {
+                        continue
+                }
+                sponsors = append(sponsors, sponsor)
+        }
+
+        return sponsors, nil
+}
+
+// enrichFromGitHubExport copies payment history from the exports onto live
+// GitHub sponsors. Export problems are logged rather than failing the live
+// fetch.
+func enrichFromGitHubExport(cfg config.Config, sponsors []Sponsor) []Sponsor {
+        rows, err := readGitHubExportDir(cfg.GitHubExportDir)
+        if err != nil {
+                log.Printf("Warning: failed to read GitHub Sponsors exports: %v", err)
+                return sponsors
+        }
+
+        history := make(map[string]Sponsor)
+        for _, sponsor := range githubSponsorsFromExport(cfg, rows, time.Now()) {
+                history[strings.ToLower(sponsor.Login)] = sponsor
+        }
+
+        for i, sponsor := range sponsors {
+                exported, ok := history[strings.ToLower(sponsor.Login)]
+                if !ok {
+                        continue
+                }
+                sponsors[i].LifetimeAmount = exported.LifetimeAmount
+                sponsors[i].LastPaymentAt = exported.LastPaymentAt
+                if sponsors[i].Months == 0 {
+                        sponsors[i].Months = exported.Months
+                }
+        }
+
+        return sponsors
+}
+
+// githubSponsorsFromExport aggregates export transactions per sponsor
+// handle. Recurring sponsors whose last payment no longer covers the
+// current billing period at now are marked as past.
+func githubSponsorsFromExport(cfg config.Config, rows []githubExportRow, now time.Time) []Sponsor {
+        bySponsor := make(map[string]*Sponsor)
+        lastYearly := make(map[string]bool)
+        var order []string
+        seen := make(map[string]bool)
+
+        for _, row := range rows {
+                handle := row.get("sponsorhandle", "sponsorlogin", "handle")
+                if handle == "" {
+                        continue
+                }
+
+                // Refunded or failed payments never count towards the total
+                status := strings.ToLower(row.get("status"))
+                if strings.Contains(status, "refund") || strings.Contains(status, "fail") ||
+                        strings.Contains(status, "declin") || strings.Contains(status, "disput") {
+                        continue
+                }
+
+                if !cfg.IncludePrivate && row.get("ispublic") != "" && !parseExportBool(row.get("ispublic")) {
+                        continue
+                }
+
+                amount := parseExportAmount(row.get("processedamount", "amount"))
+                paidAt := parseExportDate(row.get("transactiondate", "date"))
+
+                // The same transaction appears in every export that covers it
+                transactionKey := row.get("transactionid")
+                if transactionKey == "" {
+                        transactionKey = fmt.Sprintf("%s|%s|%.2f", handle, row.get("transactiondate", "date"), amount)
+                }
+                if seen[transactionKey] {
+                        continue
+                }
+                seen[transactionKey] = true
+
+                key := strings.ToLower(handle)
+                sponsor, ok := bySponsor[key]
+                if !ok {
+                        sponsor = &Sponsor{
+                                ID:        "github-export-" + key,
+                                Name:      handle,
+                                Login:     handle,
+                                AvatarURL: fmt.Sprintf("https://github.com/%s.png", handle),
+                                Link:      fmt.Sprintf("https://github.com/%s", handle),
+                                Platform:  "github",
+                                IsOneTime: true,
+                        }
+                        bySponsor[key] = sponsor
+                        order = append(order, key)
+                }
+
+                if name := row.get("sponsorprofilename", "sponsorname"); name != "" {
+                        sponsor.Name = name
+                }
+
+                sponsor.LifetimeAmount += amount
+
+                oneTime := parseExportBool(row.get("isonetime", "isonetimepayment"))
+                yearly := parseExportBool(row.get("isyearly"))
+                if !oneTime {
+                        sponsor.IsOneTime = false
+                        if yearly {
+                                sponsor.Months += 12
+                        } else {
+                                sponsor.Months++
+                        }
+                }
+
+                if started := parseExportDate(row.get("sponsorshipstartedon")); !started.IsZero() {
+                        if sponsor.CreatedAt == "" || started.Before(parseTime(sponsor.CreatedAt)) {
+                                sponsor.CreatedAt = started.Format(time.RFC3339)
+                        }
+                } else if !paidAt.IsZero() && (sponsor.CreatedAt == "" || paidAt.Before(parseTime(sponsor.CreatedAt))) {
+                        sponsor.CreatedAt = paidAt.Format(time.RFC3339)
+                }
+
+                // The latest transaction decides the current tier and amount
+                if !paidAt.IsZero() && (sponsor.LastPaymentAt == "" || paidAt.After(parseTime(sponsor.LastPaymentAt))) {
+                        sponsor.LastPaymentAt = paidAt.Format(time.RFC3339)
+                        sponsor.TierName = row.get("tiername")
+                        lastYearly[key] = yearly
+
+                        sponsor.MonthlyAmount = 0
+                        sponsor.OneTimeAmount = 0
+                        if oneTime {
+                                sponsor.OneTimeAmount = amount
+                        } else if monthly := parseExportAmount(row.get("tiermonthlyamount")); monthly > 0 {
+                                sponsor.MonthlyAmount = monthly
+                        } else if yearly {
+                                sponsor.MonthlyAmount = amount / 12
+                        } else {
+                                sponsor.MonthlyAmount = amount
+                        }
+                }
+        }
+
+        sponsors := make([]Sponsor, 0, len(order))
+        for _, key := range order {
+                sponsor := *bySponsor[key]
+                if sponsor.IsOneTime {
+                        sponsor.MonthlyAmount = 0
+                        sponsor.OneTimeAmount = sponsor.LifetimeAmount
+                } else {
+                        sponsor.OneTimeAmount = 0
+
+                        period := 31 * 24 * time.Hour
+                        if lastYearly[key] {
+                                period = 366 * 24 * time.Hour
+                        }
+                        if lastPayment := parseTime(sponsor.LastPaymentAt); lastPayment.IsZero() || now.Sub(lastPayment) > period {
+                                sponsor.Status = StatusPast
+                        }
+                }
+                sponsors = append(sponsors, sponsor)
+        }
+
+        return sponsors
+}
+
+// githubExportFiles returns the names of the .csv and .json files in dir,
+// sorted by name
+func githubExportFiles(dir string) ([]string, error) {
+        entries, err := os.ReadDir(dir)
+        if err != nil {
+                return nil, fmt.Errorf("reading GitHub export directory: %w", err)
+        }
+
+        var names []string
+        for _, entry := range entries {
+                if entry.IsDir() {
+                        continue
+                }
+                switch strings.ToLower(filepath.Ext(entry.Name())) {
+                case ".csv", ".json":
+                        names = append(names, entry.Name())
+                }
+        }
+        sort.Strings(names)
+
+        return names, nil
+}
+
+// GitHubExportDirState summarizes the name, size and modification time of
+// every export in dir. The state changes whenever an export is added,
+// removed or rewritten.
+func GitHubExportDirState(dir string) (string, error) {
+        names, err := githubExportFiles(dir)
+        if err != nil {
+                return "", err
+        }
+
+        var state strings.Builder
+        for _, name := range names {
+                info, err := os.Stat(filepath.Join(dir, name))
+                if err != nil {
+                        return "", err
+                }
+                fmt.Fprintf(&state, "%s:%d:%d\n", name, info.Size(), info.ModTime().UnixNano())
+        }
+
+        return state.String(), nil
+}
+
+// readGitHubExportDir reads the transactions of every .csv and .json file in
+// dir, in file name order
+func readGitHubExportDir(dir string) ([]githubExportRow, error) {
+        names, err := githubExportFiles(dir)
+        if err != nil {
+                return nil, err
+        }
+
+        var rows []githubExportRow
+        for _, name := range names {
+                path := filepath.Join(dir, name)
+
+                var fileRows []githubExportRow
+                if strings.ToLower(filepath.Ext(name)) == ".csv" {
+                        fileRows, err = readGitHubExportCSV(path)
+                } else {
+                        fileRows, err = readGitHubExportJSON(path)
+                }
+                if err != nil {
+                        return nil, fmt.Errorf("%s: %w", name, err)
+                }
+
+                rows = append(rows, fileRows...)
+        }
+
+        return rows, nil
+}
+
+// readGitHubExportCSV reads a CSV export. Columns are looked up by name so
+// that reordered or added columns do not break the import.
+func readGitHubExportCSV(path string) ([]githubExportRow, error) {
+        file, err := os.Open(path)
+        if err != nil {
+                return nil, err
+        }
+        defer file.Close()
+
+        reader := csv.NewReader(file)
+        reader.FieldsPerRecord = -1
+
+        header, err := reader.Read()
+        if err == io.EOF {
+                return nil, nil
+        }
+        if err != nil {
+                return nil, fmt.Errorf("failed to read CSV header: %w", err)
+        }
+
+        columns := make([]string, len(header))
+        for i, name := range header {
+                columns[i] = normalizeExportColumn(name)
+        }
+
+        var rows []githubExportRow
+        for {
+                record, err := reader.Read()
+                if err == io.EOF {
+                        break
+                }
+                if err != nil {
+                        return nil, fmt.Errorf("failed to read CSV: %w", err)
+                }
+
+                row := make(githubExportRow)
+                for i, value := range record {
+                        if i < len(columns) {
+                                row[columns[i]] = strings.TrimSpace(value)
+                        }
+                }
+                rows = append(rows, row)
+        }
+
+        return rows, nil
+}
+
+// readGitHubExportJSON reads a JSON export, which is an array of objects
+// with the same fields as the CSV export
+func readGitHubExportJSON(path string) ([]githubExportRow, error) {
+        data, err := os.ReadFile(path)
+        if err != nil {
+                return nil, err
+        }
+
+        var records []map[string]interface{}
+        if err := json.Unmarshal(data, &records); err != nil {
+                return nil, fmt.Errorf("failed to decode JSON: %w", err)
+        }
+
+        rows := make([]githubExportRow, 0, len(records))
+        for _, record := range records {
+                row := make(githubExportRow)
+                for name, value := range record {
+                        if value == nil {
+                                continue
+                        }
+                        row[normalizeExportColumn(name)] = strings.TrimSpace(fmt.Sprint(value))
+                }
+                rows = append(rows, row)
+        }
+
+        return rows, nil
+}
+
+// normalizeExportColumn lowercases a column name and drops everything but
+// letters and digits, so "Sponsor Handle" and "sponsor_handle" match
+func normalizeExportColumn(name string) string {
+        var b strings.Builder
+        for _, r := range strings.ToLower(name) {
+                if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+                        b.WriteRune(r)
+                }
+        }
+        return b.String()
+}
+
+// parseExportAmount parses an amount such as "$1,234.50", returning 0 if it
+// is empty or malformed
+func parseExportAmount(value string) float64 {
+        cleaned := strings.Map(func(r rune) rune {
+                if (r >= '0' && r <= '9') || r == '.' || r == '-' {
+                        return r
+                }
+                return -1
+        }, value)
+
+        amount, err := strconv.ParseFloat(cleaned, 64)
+        if err != nil {
+                return 0
+        }
+        return amount
+}
+
+// parseExportBool parses the yes/no columns of an export
+func parseExportBool(value string) bool {
+        switch strings.ToLower(strings.TrimSpace(value)) {
+        case "true", "yes", "y", "1":
+                return true
+        }
+        return false
+}
+
+// parseExportDate parses a date in any of the formats used by exports,
+// returning the zero time if it is empty or malformed
+func parseExportDate(value string) time.Time {
+        for _, layout := range githubExportDateLayouts {
+                if t, err := time.Parse(layout, value); err == nil {
+                        return t
+                }
+        }
+        return time.Time{}
+}
@@ -0,0 +1,139 @@
+package sponsors
+
+import (
+        "strings"
+
+        "sponsorgen/config"
+)
+
+// GitHubSponsorshipEvent represents the payload of a GitHub "sponsorship" webhook
+type GitHubSponsorshipEvent struct {
+        Action      string `json:"action"`
+        Sponsorship struct {
+                NodeID       string `json:"node_id"`
+                CreatedAt    string `json:"created_at"`
+                PrivacyLevel string `json:"privacy_level"`
+                Sponsor      struct {
+                        Login     string `json:"login"`
+                        NodeID    string `json:"node_id"`
+                        AvatarURL string `json:"avatar_url"`
+                        HTMLURL   string `json:"html_url"`
+                } `json:"sponsor"`
+                Tier GitHubWebhookTier `json:"tier"`
+        } `json:"sponsorship"`
+        EffectiveDate string `json:"effective_date"`
+}
+
+// GitHubWebhookTier represents a tier as sent in GitHub webhooks
+type GitHubWebhookTier struct {
+        NodeID              string `json:"node_id"`
+        Name                string `json:"name"`
+        MonthlyPriceInCents int    `json:"monthly_price_in_cents"`
+        IsOneTime           bool   `json:"is_one_time"`
+        IsCustomAmount      bool   `json:"is_custom_amount"`
+}
+
+// sponsor converts the sponsorship of the event into a Sponsor
+func (e GitHubSponsorshipEvent) sponsor() Sponsor {
+        sponsorship := e.Sponsorship
+        return githubSponsorFromNode(GitHubSponsorship{
+                CreatedAt: sponsorship.CreatedAt,
+                IsActive:  true,
+                IsOneTime: sponsorship.Tier.IsOneTime,
+                Tier: &GitHubSponsorsTier{
+                        ID:                  sponsorship.Tier.NodeID,
+                        Name:                sponsorship.Tier.Name,
+                        MonthlyPriceInCents: sponsorship.Tier.MonthlyPriceInCents,
+                        IsOneTime:           sponsorship.Tier.IsOneTime,
+                        IsCustomAmount:      sponsorship.Tier.IsCustomAmount,
+                },
+                Sponsor: GitHubSponsorEntity{
+                        ID:        sponsorship.Sponsor.NodeID,
+                        Login:     sponsorship.Sponsor.Login,
+                        Name:      sponsorship.Sponsor.Login,
+                        AvatarURL: sponsorship.Sponsor.AvatarURL,
+                        URL:       sponsorship.Sponsor.HTMLURL,
+                },
+        })
+}
+
+// ApplyGitHubSponsorshipEvent applies a sponsorship webhook to a list of
+// sponsors fetched by FetchAll. It returns the updated list and whether
+// anything changed. Pending cancellations only take effect once GitHub
+// sends the cancelled event, so they leave the list unchanged.
+func ApplyGitHubSponsorshipEvent(cfg config.Config, list []Sponsor, event GitHubSponsorshipEvent) ([]Sponsor, bool) {
+        login := event.Sponsorship.Sponsor.Login
+        if login == "" {
+                return list, false
+        }
+
+        if event.Sponsorship.PrivacyLevel == "private" && !cfg.IncludePrivate {
+                return list, false
+        }
+
+        index := -1
+        for i, existing := range list {
+                if existing.Platform == "github" && strings.EqualFold(existing.Login, login) {
+                        index = i
+                        break
+                }
+        }
+
+        switch event.Action {
+        case "created":
+                sponsor := event.sponsor()
+                if sponsor.IsOneTime && !includeOneTime(cfg, parseTime(sponsor.CreatedAt)) {
+                        return list, false
+                }
+                sponsor.Status = StatusActive
+
+                if index >= 0 {
+                        // Webhooks only carry the login, keep the display name
+                        // from GraphQL
+                        if list[index].Name != "" {
+                                sponsor.Name = list[index].Name
+                        }
+
+                        // Keep what the export knows about earlier payments
+                        sponsor.LifetimeAmount = list[index].LifetimeAmount
+                        sponsor.LastPaymentAt = list[index].LastPaymentAt
+                        sponsor.Months = list[index].Months
+                        list[index] = sponsor
+                } else {
+                        list = append(list, sponsor)
+                }
+                return list, true
+
+        case "tier_changed":
+                sponsor := event.sponsor()
+                if index < 0 {
+                        sponsor.Status = StatusActive
+                        return append(list, sponsor), true
+                }
+
+                list[index].TierID = sponsor.TierID
+                list[index].TierName = sponsor.TierName
+                list[index].MonthlyAmount = sponsor.MonthlyAmount
+                list[index].OneTimeAmount = sponsor.OneTimeAmount
+                list[index].IsOneTime = sponsor.IsOneTime
+                list[index].IsCustomAmount = sponsor.IsCustomAmount
+                return list, true
+
+        case "cancelled":
+                if index < 0 || list[index].IsPast() {
+                        return list, false
+                }
+
+                if cfg.IncludePastSponsors && !list[index].IsOneTime {
+                        list[index].Status = StatusPast
+                        return list, true
+                }
+
+                return append(list[:index], list[index+1:]...), true
+
+        default:
+                // pending_cancellation, pending_tier_change and edited do not
+                // change what is shown
+                return list, false
+        }
+}
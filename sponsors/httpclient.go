@@ -0,0 +1,259 @@
+package sponsors
+
+import (
+        "context"
+        "io"
+        "log"
+        "math/rand"
+        "net/http"
+        "strconv"
+        "time"
+
+        "sponsorgen/config"
+)
+
+const (
+        // retryBaseDelay is the backoff before the first retry, doubled for
+        // every further attempt
+        retryBaseDelay = 1 * time.Second
+        // retryMaxDelay caps the backoff between two attempts
+        retryMaxDelay = 30 * time.Second
+        // retryMaxWait is the longest a Retry-After or rate limit reset is
+        // waited for; longer waits give up instead of stalling the refresh
+        retryMaxWait = 60 * time.Second
+)
+
+// retryableKey marks non-GET requests that are safe to send again
+type retryableKey struct{}
+
+// markRetryable flags a request that does not change anything on the
+// server, such as a GraphQL query sent as POST, so that it is retried like
+// a GET request
+func markRetryable(req *http.Request) *http.Request {
+        return req.WithContext(context.WithValue(req.Context(), retryableKey{}, true))
+}
+
+// newHTTPClient returns the HTTP client used by the providers. Each attempt
+// is limited to timeout, and idempotent requests that fail with a network
+// error, a 429 or a 5xx response are retried up to HTTPMaxRetries times with
+// jittered exponential backoff. Retry-After and the X-RateLimit headers of
+// GitHub and others are honored.
+func newHTTPClient(cfg config.Config, timeout time.Duration) *http.Client {
+        return &http.Client{
+                Transport: &retryTransport{
+                        base:       http.DefaultTransport,
+                        maxRetries: cfg.HTTPMaxRetries,
+                        timeout:    timeout,
+                },
+        }
+}
+
+// retryTransport is an http.RoundTripper that retries failed requests
+type retryTransport struct {
+        base       http.RoundTripper
+        maxRetries int
+        timeout    time.Duration
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+        retryable := isRetryable(req)
+
+        for attempt := 0; ; attempt++ {
+                // Later attempts send a fresh copy of the body
+                attemptReq := req
+                if attempt > 0 && req.GetBody != nil {
+                        body, err := req.GetBody()
+                        if err != nil {
+                                return nil, err
+                        }
+                        attemptReq = req.Clone(req.Context())
+                        attemptReq.Body = body
+                }
+
+                resp, err := t.attempt(attemptReq)
+
+                if resp != nil {
+                        logRateLimitExhausted(req, resp)
+                }
+
+                if !retryable || req.Context().Err() != nil {
+                        return resp, err
+                }
+
+                wait, retry := retryDelay(resp, err, attempt)
+                if !retry {
+                        return resp, err
+                }
+
+                if attempt >= t.maxRetries {
+                        log.Printf("Giving up on %s %s after %d attempts: %s", req.Method, req.URL.Host, attempt+1, describeAttempt(resp, err))
+                        return resp, err
+                }
+
+                if wait > retryMaxWait {
+                        log.Printf("Not retrying %s %s: server asked to wait %s, longer than %s", req.Method, req.URL.Host, wait.Round(time.Second), retryMaxWait)
+                        return resp, err
+                }
+
+                log.Printf("Retrying %s %s in %s (attempt %d of %d): %s", req.Method, req.URL.Host, wait.Round(time.Millisecond), attempt+2, t.maxRetries+1, describeAttempt(resp, err))
+
+                // Discard the failed response so its connection can be reused
+                if resp != nil {
+                        io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+                        resp.Body.Close()
+                }
+
+                timer := time.NewTimer(wait)
+                select {
+                case <-req.Context().Done():
+                        timer.Stop()
+                        return nil, req.Context().Err()
+                case <-timer.C:
+                }
+        }
+}
+
+// attempt sends a single request limited to the per-attempt timeout. The
+// timeout keeps running while the caller reads the body.
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+        if t.timeout <= 0 {
+                return t.base.RoundTrip(req)
+        }
+
+        ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+        resp, err := t.base.RoundTrip(req.WithContext(ctx))
+        if err != nil {
+                cancel()
+                return nil, err
+        }
+
+        resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+        return resp, nil
+}
+
+// cancelOnClose releases the context of an attempt once its body is closed
+type cancelOnClose struct {
+        io.ReadCloser
+        cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+        err := c.ReadCloser.Close()
+        c.cancel()
+        return err
+}
+
+// isRetryable reports whether req can safely be sent more than once
+func isRetryable(req *http.Request) bool {
+        if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+                return false
+        }
+
+        switch req.Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+                return true
+        }
+
+        marked, _ := req.Context().Value(retryableKey{}).(bool)
+        return marked
+}
+
+// retryDelay decides whether a failed attempt should be retried and how
+// long to wait before doing so
+func retryDelay(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+        if err != nil {
+                return backoff(attempt), true
+        }
+
+        switch {
+        case resp.StatusCode == http.StatusTooManyRequests:
+        case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+        case resp.StatusCode == http.StatusForbidden && (resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"):
+                // GitHub reports primary and secondary rate limits as 403
+        default:
+                return 0, false
+        }
+
+        if wait, ok := retryAfter(resp.Header); ok {
+                return wait, true
+        }
+        if wait, ok := rateLimitReset(resp.Header); ok {
+                return wait, true
+        }
+        return backoff(attempt), true
+}
+
+// backoff returns the jittered exponential backoff for an attempt, between
+// half and all of the doubled base delay
+func backoff(attempt int) time.Duration {
+        delay := retryBaseDelay << uint(attempt)
+        if delay <= 0 || delay > retryMaxDelay {
+                delay = retryMaxDelay
+        }
+        return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as an HTTP date
+func retryAfter(header http.Header) (time.Duration, bool) {
+        value := header.Get("Retry-After")
+        if value == "" {
+                return 0, false
+        }
+
+        if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+                return time.Duration(seconds) * time.Second, true
+        }
+
+        if date, err := http.ParseTime(value); err == nil {
+                wait := time.Until(date)
+                if wait < 0 {
+                        wait = 0
+                }
+                return wait, true
+        }
+
+        return 0, false
+}
+
+// rateLimitReset returns the time until the rate limit resets when the
+// remaining budget is used up
+func rateLimitReset(header http.Header) (time.Duration, bool) {
+        if header.Get("X-RateLimit-Remaining") != "0" {
+                return 0, false
+        }
+
+        reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+        if err != nil {
+                return 0, false
+        }
+
+        wait := time.Until(time.Unix(reset, 0))
+        if wait < 0 {
+                wait = 0
+        }
+        return wait, true
+}
+
+// logRateLimitExhausted logs when a response reports that the rate limit
+// budget is used up, so that failures of later requests can be explained
+func logRateLimitExhausted(req *http.Request, resp *http.Response) {
+        if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+                return
+        }
+
+        resetAt := "an unknown time"
+        if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+                resetAt = time.Unix(reset, 0).Format(time.RFC3339)
+        }
+
+        log.Printf("Warning: rate limit for %s exhausted (limit %s), resets at %s", req.URL.Host, resp.Header.Get("X-RateLimit-Limit"), resetAt)
+}
+
+// describeAttempt summarizes the outcome of a failed attempt for logging
+func describeAttempt(resp *http.Response, err error) string {
+        if err != nil {
+                return err.Error()
+        }
+        return resp.Status
+}
@@ -0,0 +1,256 @@
+package sponsors
+
+import (
+        "bufio"
+        "context"
+        "crypto/md5"
+        "encoding/hex"
+        "encoding/json"
+        "fmt"
+        "os"
+        "path/filepath"
+        "sort"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(kofiProvider{})
+}
+
+// kofiProvider turns the Ko-fi webhook ledger into sponsors
+type kofiProvider struct{}
+
+func (kofiProvider) Name() string { return "kofi" }
+
+func (kofiProvider) Enabled(cfg config.Config) bool {
+        return cfg.KofiVerificationToken != ""
+}
+
+func (kofiProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchKofiSponsors(ctx, cfg)
+}
+
+// kofiLedgerFile is the name of the Ko-fi webhook ledger inside CacheDir
+const kofiLedgerFile = "kofi_ledger.jsonl"
+
+// kofiSubscriptionPeriod is how long a subscription payment keeps a
+// supporter active
+const kofiSubscriptionPeriod = 31 * 24 * time.Hour
+
+// kofiLedgerMutex serializes access to the ledger file
+var kofiLedgerMutex sync.Mutex
+
+// KofiEvent represents the payload of a Ko-fi webhook
+type KofiEvent struct {
+        VerificationToken          string `json:"verification_token"`
+        MessageID                  string `json:"message_id"`
+        Timestamp                  string `json:"timestamp"`
+        Type                       string `json:"type"` // Donation, Subscription, Commission or Shop Order
+        IsPublic                   bool   `json:"is_public"`
+        FromName                   string `json:"from_name"`
+        Message                    string `json:"message"`
+        Amount                     string `json:"amount"`
+        URL                        string `json:"url"`
+        Email                      string `json:"email"`
+        Currency                   string `json:"currency"`
+        IsSubscriptionPayment      bool   `json:"is_subscription_payment"`
+        IsFirstSubscriptionPayment bool   `json:"is_first_subscription_payment"`
+        KofiTransactionID          string `json:"kofi_transaction_id"`
+        TierName                   string `json:"tier_name"`
+}
+
+// AppendKofiEvent appends a verified webhook event to the Ko-fi ledger. The
+// verification token is not stored.
+func AppendKofiEvent(cfg config.Config, event KofiEvent) error {
+        kofiLedgerMutex.Lock()
+        defer kofiLedgerMutex.Unlock()
+
+        event.VerificationToken = ""
+        line, err := json.Marshal(event)
+        if err != nil {
+                return fmt.Errorf("encoding Ko-fi event: %w", err)
+        }
+
+        if err := os.MkdirAll(cfg.CacheDir, 0755); err != nil {
+                return fmt.Errorf("creating cache directory: %w", err)
+        }
+
+        file, err := os.OpenFile(filepath.Join(cfg.CacheDir, kofiLedgerFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+        if err != nil {
+                return fmt.Errorf("opening Ko-fi ledger: %w", err)
+        }
+        defer file.Close()
+
+        if _, err := file.Write(append(line, '\n')); err != nil {
+                return fmt.Errorf("writing Ko-fi ledger: %w", err)
+        }
+
+        return nil
+}
+
+// FetchKofiSponsors builds sponsors from the Ko-fi webhook ledger.
+// Subscribers are active for a month after each payment; donations follow
+// the one-time policy.
+func FetchKofiSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        events, err := readKofiLedger(cfg)
+        if err != nil {
+                return nil, err
+        }
+
+        // Group payments by supporter
+        bySupporter := make(map[string][]KofiEvent)
+        for _, event := range events {
+                if event.Type != "Donation" && event.Type != "Subscription" {
+                        continue
+                }
+                key := kofiSupporterKey(event)
+                bySupporter[key] = append(bySupporter[key], event)
+        }
+
+        keys := make([]string, 0, len(bySupporter))
+        for key := range bySupporter {
+                keys = append(keys, key)
+        }
+        sort.Strings(keys)
+
+        var sponsors []Sponsor
+        now := time.Now()
+        for _, key := range keys {
+                if sponsor, ok := kofiSponsorFromEvents(cfg, key, bySupporter[key], now); ok {
+                        sponsors = append(sponsors, sponsor)
+                }
+        }
+
+        return sponsors, nil
+}
+
+// kofiSponsorFromEvents combines the payments of a supporter into a
+// Sponsor. It returns false for supporters that should not be shown.
+func kofiSponsorFromEvents(cfg config.Config, key string, events []KofiEvent, now time.Time) (Sponsor, bool) {
+        sort.Slice(events, func(i, j int) bool {
+                return events[i].Timestamp < events[j].Timestamp
+        })
+        latest := events[len(events)-1]
+
+        // Respect the supporter's visibility choice on their latest payment
+        name := latest.FromName
+        if !latest.IsPublic {
+                if !cfg.IncludePrivate {
+                        return Sponsor{}, false
+                }
+                name = "Anonymous Supporter"
+        }
+
+        sponsor := Sponsor{
+                ID:            "kofi-" + key,
+                Name:          name,
+                Login:         name,
+                Platform:      "kofi",
+                CreatedAt:     events[0].Timestamp,
+                LastPaymentAt: latest.Timestamp,
+                Status:        StatusActive,
+        }
+
+        var latestSubscription *KofiEvent
+        oneTimeTotal := 0.0
+        for i, event := range events {
+                amount, _ := strconv.ParseFloat(event.Amount, 64)
+                sponsor.LifetimeAmount += amount
+
+                if event.IsSubscriptionPayment {
+                        latestSubscription = &events[i]
+                } else if includeOneTime(cfg, parseTime(event.Timestamp)) {
+                        oneTimeTotal += amount
+                }
+        }
+
+        if latestSubscription != nil {
+                amount, _ := strconv.ParseFloat(latestSubscription.Amount, 64)
+                sponsor.MonthlyAmount = amount
+                sponsor.TierName = latestSubscription.TierName
+
+                if now.Sub(parseTime(latestSubscription.Timestamp)) > kofiSubscriptionPeriod {
+                        sponsor.Status = StatusPast
+                }
+        }
+
+        sponsor.OneTimeAmount = oneTimeTotal
+
+        switch {
+        case latestSubscription != nil && sponsor.Status == StatusActive:
+                return sponsor, true
+        case oneTimeTotal > 0:
+                // Lapsed subscribers who also donated are shown for their donations
+                sponsor.Status = StatusActive
+                sponsor.MonthlyAmount = 0
+                sponsor.IsOneTime = true
+                return sponsor, true
+        case latestSubscription != nil && cfg.IncludePastSponsors:
+                return sponsor, true
+        }
+
+        return Sponsor{}, false
+}
+
+// readKofiLedger reads every event from the ledger, skipping duplicate
+// deliveries of the same transaction
+func readKofiLedger(cfg config.Config) ([]KofiEvent, error) {
+        kofiLedgerMutex.Lock()
+        defer kofiLedgerMutex.Unlock()
+
+        file, err := os.Open(filepath.Join(cfg.CacheDir, kofiLedgerFile))
+        if os.IsNotExist(err) {
+                return nil, nil
+        }
+        if err != nil {
+                return nil, fmt.Errorf("opening Ko-fi ledger: %w", err)
+        }
+        defer file.Close()
+
+        var events []KofiEvent
+        seen := make(map[string]bool)
+
+        scanner := bufio.NewScanner(file)
+        for scanner.Scan() {
+                line := strings.TrimSpace(scanner.Text())
+                if line == "" {
+                        continue
+                }
+
+                var event KofiEvent
+                if err := json.Unmarshal([]byte(line), &event); err != nil {
+                        return nil, fmt.Errorf("parsing Ko-fi ledger: %w", err)
+                }
+
+                if event.KofiTransactionID != "" {
+                        if seen[event.KofiTransactionID] {
+                                continue
+                        }
+                        seen[event.KofiTransactionID] = true
+                }
+
+                events = append(events, event)
+        }
+
+        if err := scanner.Err(); err != nil {
+                return nil, fmt.Errorf("reading Ko-fi ledger: %w", err)
+        }
+
+        return events, nil
+}
+
+// kofiSupporterKey identifies a supporter without exposing their email
+func kofiSupporterKey(event KofiEvent) string {
+        identity := strings.ToLower(strings.TrimSpace(event.Email))
+        if identity == "" {
+                identity = strings.ToLower(strings.TrimSpace(event.FromName))
+        }
+
+        hash := md5.Sum([]byte(identity))
+        return hex.EncodeToString(hash[:])[:12]
+}
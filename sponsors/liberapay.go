@@ -0,0 +1,127 @@
+package sponsors
+
+import (
+        "context"
+        "encoding/csv"
+        "fmt"
+        "io"
+        "net/http"
+        "strconv"
+        "strings"
+        "time"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(liberapayProvider{})
+}
+
+// liberapayProvider fetches public patrons from Liberapay
+type liberapayProvider struct{}
+
+func (liberapayProvider) Name() string { return "liberapay" }
+
+func (liberapayProvider) Enabled(cfg config.Config) bool {
+        return cfg.LiberapayUsername != ""
+}
+
+func (liberapayProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchLiberapaySponsors(ctx, cfg)
+}
+
+// weeksPerMonth converts Liberapay's weekly amounts to monthly ones
+const weeksPerMonth = 52.0 / 12.0
+
+// FetchLiberapaySponsors fetches the public patrons of a Liberapay user or
+// team. Liberapay publishes them as CSV, so no token is needed.
+func FetchLiberapaySponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        sponsors := []Sponsor{}
+
+        client := newHTTPClient(cfg, 10*time.Second)
+
+        url := fmt.Sprintf("%s/%s/patrons/public.csv", cfg.LiberapayAPIURL, cfg.LiberapayUsername)
+
+        req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+        if err != nil {
+                return sponsors, fmt.Errorf("failed to create Liberapay request: %w", err)
+        }
+
+        resp, err := client.Do(req)
+        if err != nil {
+                return sponsors, fmt.Errorf("failed to execute Liberapay request: %w", err)
+        }
+        defer resp.Body.Close()
+
+        if resp.StatusCode != http.StatusOK {
+                body, _ := io.ReadAll(resp.Body)
+                return sponsors, fmt.Errorf("Liberapay request failed with status %d: %s", resp.StatusCode, string(body))
+        }
+
+        reader := csv.NewReader(resp.Body)
+        header, err := reader.Read()
+        if err == io.EOF {
+                return sponsors, nil
+        }
+        if err != nil {
+                return sponsors, fmt.Errorf("failed to read Liberapay CSV header: %w", err)
+        }
+
+        columns := make(map[string]int)
+        for i, name := range header {
+                columns[strings.TrimSpace(name)] = i
+        }
+        for _, required := range []string{"patron_id", "patron_username", "weekly_amount"} {
+                if _, ok := columns[required]; !ok {
+                        return sponsors, fmt.Errorf("Liberapay CSV is missing the %s column", required)
+                }
+        }
+
+        for {
+                record, err := reader.Read()
+                if err == io.EOF {
+                        break
+                }
+                if err != nil {
+                        return sponsors, fmt.Errorf("failed to read Liberapay CSV: %w", err)
+                }
+
+                field := func(name string) string {
+                        if i, ok := columns[name]; ok && i < len(record) {
+                                return strings.TrimSpace(record[i])
+                        }
+                        return ""
+                }
+
+                weeklyAmount, err := strconv.ParseFloat(field("weekly_amount"), 64)
+                if err != nil || weeklyAmount <= 0 {
+                        continue
+                }
+
+                username := field("patron_username")
+                name := field("patron_public_name")
+                if name == "" {
+                        name = username
+                }
+
+                createdAt := field("pledge_date")
+                if date, err := time.Parse("2006-01-02", createdAt); err == nil {
+                        createdAt = date.Format(time.RFC3339)
+                }
+
+                sponsor := Sponsor{
+                        ID:            field("patron_id"),
+                        Name:          name,
+                        Login:         username,
+                        AvatarURL:     field("patron_avatar_url"),
+                        Link:          fmt.Sprintf("%s/%s", cfg.LiberapayAPIURL, username),
+                        Platform:      "liberapay",
+                        MonthlyAmount: weeklyAmount * weeksPerMonth,
+                        CreatedAt:     createdAt,
+                }
+
+                sponsors = append(sponsors, sponsor)
+        }
+
+        return sponsors, nil
+}
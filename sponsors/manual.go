@@ -0,0 +1,153 @@
+package sponsors
+
+import (
+        "context"
+        "fmt"
+        "os"
+        "path/filepath"
+        "strings"
+        "time"
+
+        "gopkg.in/yaml.v2"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(manualProvider{})
+}
+
+// manualProvider reads sponsors from a YAML file, for sponsors who pay by
+// invoice or bank transfer
+type manualProvider struct{}
+
+func (manualProvider) Name() string { return "manual" }
+
+func (manualProvider) Enabled(cfg config.Config) bool {
+        return cfg.ManualSponsorsFile != ""
+}
+
+func (manualProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return LoadManualSponsors(ctx, cfg)
+}
+
+// ManualSponsorsFile represents the sponsors YAML file
+type ManualSponsorsFile struct {
+        Sponsors []ManualSponsor `yaml:"sponsors"`
+}
+
+// ManualSponsor represents a sponsor entry in the sponsors YAML file
+type ManualSponsor struct {
+        Name          string  `yaml:"name"`
+        Login         string  `yaml:"login"`
+        Avatar        string  `yaml:"avatar"` // URL or path relative to the YAML file
+        Link          string  `yaml:"link"`
+        MonthlyAmount float64 `yaml:"monthly_amount"`
+        Tier          string  `yaml:"tier"`
+        Start         string  `yaml:"start"` // YYYY-MM-DD or RFC 3339
+        End           string  `yaml:"end"`   // optional, YYYY-MM-DD or RFC 3339
+}
+
+// LoadManualSponsors reads the sponsors YAML file. It is read again on every
+// refresh so edits show up without a restart.
+func LoadManualSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        data, err := os.ReadFile(cfg.ManualSponsorsFile)
+        if err != nil {
+                return nil, fmt.Errorf("reading sponsors file: %w", err)
+        }
+
+        var file ManualSponsorsFile
+        if err := yaml.UnmarshalStrict(data, &file); err != nil {
+                return nil, fmt.Errorf("parsing sponsors file: %w", err)
+        }
+
+        baseDir, err := filepath.Abs(filepath.Dir(cfg.ManualSponsorsFile))
+        if err != nil {
+                return nil, fmt.Errorf("resolving sponsors file directory: %w", err)
+        }
+
+        sponsors := []Sponsor{}
+        now := time.Now()
+
+        for i, entry := range file.Sponsors {
+                if entry.Name == "" && entry.Login == "" {
+                        return nil, fmt.Errorf("sponsors file entry %d has neither name nor login", i+1)
+                }
+
+                start, err := parseManualDate(entry.Start)
+                if err != nil {
+                        return nil, fmt.Errorf("sponsors file entry %d: invalid start date: %w", i+1, err)
+                }
+                end, err := parseManualDate(entry.End)
+                if err != nil {
+                        return nil, fmt.Errorf("sponsors file entry %d: invalid end date: %w", i+1, err)
+                }
+
+                // Not started yet
+                if !start.IsZero() && start.After(now) {
+                        continue
+                }
+
+                status := StatusActive
+                if !end.IsZero() && end.Before(now) {
+                        if !cfg.IncludePastSponsors {
+                                continue
+                        }
+                        status = StatusPast
+                }
+
+                name := entry.Name
+                if name == "" {
+                        name = entry.Login
+                }
+                login := entry.Login
+                if login == "" {
+                        login = name
+                }
+
+                createdAt := ""
+                if !start.IsZero() {
+                        createdAt = start.Format(time.RFC3339)
+                }
+
+                sponsors = append(sponsors, Sponsor{
+                        ID:            "manual-" + strings.ToLower(login),
+                        Name:          name,
+                        Login:         login,
+                        AvatarURL:     resolveManualAvatar(entry.Avatar, baseDir),
+                        Link:          entry.Link,
+                        Platform:      "manual",
+                        MonthlyAmount: entry.MonthlyAmount,
+                        CreatedAt:     createdAt,
+                        TierName:      entry.Tier,
+                        Status:        status,
+                })
+        }
+
+        return sponsors, nil
+}
+
+// parseManualDate parses a date in YYYY-MM-DD or RFC 3339 format. Empty
+// values yield the zero time.
+func parseManualDate(value string) (time.Time, error) {
+        value = strings.TrimSpace(value)
+        if value == "" {
+                return time.Time{}, nil
+        }
+        if t, err := time.Parse("2006-01-02", value); err == nil {
+                return t, nil
+        }
+        return time.Parse(time.RFC3339, value)
+}
+
+// resolveManualAvatar turns local avatar paths into absolute paths so they
+// are read from disk when the SVG is generated
+func resolveManualAvatar(avatar, baseDir string) string {
+        if avatar == "" || strings.Contains(avatar, "://") || strings.HasPrefix(avatar, "data:") {
+                return avatar
+        }
+        if filepath.IsAbs(avatar) {
+                return avatar
+        }
+        return filepath.Join(baseDir, avatar)
+}
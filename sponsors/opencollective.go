@@ -2,59 +2,91 @@ package sponsors
 
 import (
         "bytes"
+        "context"
         "encoding/json"
         "fmt"
         "io"
+        "log"
         "net/http"
         "time"
 
         "sponsorgen/config"
 )
 
+func init() {
+        Register(openCollectiveProvider{})
+}
+
+// openCollectiveProvider fetches backers from OpenCollective
+type openCollectiveProvider struct{}
+
+func (openCollectiveProvider) Name() string { return "opencollective" }
+
+func (openCollectiveProvider) Enabled(cfg config.Config) bool {
+        return cfg.OpenCollectiveSlug != ""
+}
+
+func (openCollectiveProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchOpenCollectiveSponsors(ctx, cfg)
+}
+
+const (
+        // openCollectivePageSize is the number of orders requested per page
+        openCollectivePageSize = 100
+        // openCollectiveMaxPages caps pagination in case the API keeps
+        // reporting more orders than it returns
+        openCollectiveMaxPages = 100
+)
+
 // OpenCollectiveResponse represents the OpenCollective API response
 type OpenCollectiveResponse struct {
         Data struct {
                 Account struct {
                         Orders struct {
-                                Nodes []struct {
-                                        FromAccount struct {
-                                                ID        string `json:"id"`
-                                                Name      string `json:"name"`
-                                                Slug      string `json:"slug"`
-                                                ImageURL  string `json:"imageUrl"`
-                                                Website   string `json:"website"`
-                                                Company   string `json:"company"`
-                                                IsActive  bool   `json:"isActive"`
-                                                CreatedAt string `json:"createdAt"`
-                                        } `json:"fromAccount"`
-                                        Status     string    `json:"status"`
-                                        Amount     struct {
-                                                Value    float64 `json:"value"`
-                                                Currency string  `json:"currency"`
-                                        } `json:"amount"`
-                                        Frequency   string `json:"frequency"`
-                                        TotalAmount struct {
-                                                Value    float64 `json:"value"`
-                                                Currency string  `json:"currency"`
-                                        } `json:"totalAmount"`
-                                        CreatedAt string `json:"createdAt"`
-                                        Tier      struct {
-                                                Name string `json:"name"`
-                                        } `json:"tier"`
-                                } `json:"nodes"`
+                                TotalCount int                   `json:"totalCount"`
+                                Nodes      []OpenCollectiveOrder `json:"nodes"`
                         } `json:"orders"`
                 } `json:"account"`
         } `json:"data"`
 }
 
+// OpenCollectiveOrder represents an incoming order of an OpenCollective account
+type OpenCollectiveOrder struct {
+        FromAccount struct {
+                ID        string `json:"id"`
+                Name      string `json:"name"`
+                Slug      string `json:"slug"`
+                ImageURL  string `json:"imageUrl"`
+                Website   string `json:"website"`
+                Company   string `json:"company"`
+                IsActive  bool   `json:"isActive"`
+                CreatedAt string `json:"createdAt"`
+        } `json:"fromAccount"`
+        Status     string    `json:"status"`
+        Amount     struct {
+                Value    float64 `json:"value"`
+                Currency string  `json:"currency"`
+        } `json:"amount"`
+        Frequency   string `json:"frequency"`
+        TotalAmount struct {
+                Value    float64 `json:"value"`
+                Currency string  `json:"currency"`
+        } `json:"totalAmount"`
+        CreatedAt string `json:"createdAt"`
+        Tier      struct {
+                Name string `json:"name"`
+        } `json:"tier"`
+}
+
 // FetchOpenCollectiveSponsors fetches sponsors from OpenCollective
-func FetchOpenCollectiveSponsors(cfg config.Config) ([]Sponsor, error) {
+func FetchOpenCollectiveSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
         sponsors := []Sponsor{}
 
         query := `
-        query($slug: String!) {
+        query($slug: String!, $status: [OrderStatus], $limit: Int!, $offset: Int!) {
                 account(slug: $slug) {
-                        orders(status: ACTIVE, filter: INCOMING) {
+                        orders(status: $status, filter: INCOMING, limit: $limit, offset: $offset) {
+                                totalCount
                                 nodes {
                                         fromAccount {
                                                 id
@@ -86,28 +118,80 @@ func FetchOpenCollectiveSponsors(cfg config.Config) ([]Sponsor, error) {
         }
         `
 
-        client := &http.Client{
-                Timeout: 10 * time.Second,
+        client := newHTTPClient(cfg, 10*time.Second)
+
+        // Recurring contributions stay ACTIVE, one-time orders are PAID
+        status := []string{"ACTIVE"}
+        if cfg.OneTimePolicy != config.OneTimeExclude {
+                status = append(status, "PAID")
         }
 
-        variables := map[string]interface{}{
-                "slug": cfg.OpenCollectiveSlug,
+        var orders []OpenCollectiveOrder
+        totalCount := 0
+        pages := 0
+
+        for offset := 0; ; offset += openCollectivePageSize {
+                if pages >= openCollectiveMaxPages {
+                        log.Printf("Warning: stopped paginating OpenCollective orders after %d pages (%d of %d orders fetched)", pages, len(orders), totalCount)
+                        break
+                }
+
+                variables := map[string]interface{}{
+                        "slug":   cfg.OpenCollectiveSlug,
+                        "status": status,
+                        "limit":  openCollectivePageSize,
+                        "offset": offset,
+                }
+
+                response, err := fetchOpenCollectivePage(ctx, client, cfg, query, variables)
+                if err != nil {
+                        return sponsors, err
+                }
+                pages++
+
+                page := response.Data.Account.Orders
+                totalCount = page.TotalCount
+                orders = append(orders, page.Nodes...)
+
+                // Stop once every order has been fetched or the API returns a short page
+                if len(page.Nodes) < openCollectivePageSize || offset+len(page.Nodes) >= totalCount {
+                        break
+                }
         }
 
+        log.Printf("Fetched %d OpenCollective orders in %d pages", len(orders), pages)
+
+        // Process sponsors
+        for _, order := range orders {
+                if sponsor, ok := openCollectiveSponsorFromOrder(cfg, order); ok {
+                        sponsors = append(sponsors, sponsor)
+                }
+        }
+
+        return sponsors, nil
+}
+
+// fetchOpenCollectivePage executes a single OpenCollective GraphQL request
+func fetchOpenCollectivePage(ctx context.Context, client *http.Client, cfg config.Config, query string, variables map[string]interface{}) (OpenCollectiveResponse, error) {
+        var response OpenCollectiveResponse
+
         requestBody, err := json.Marshal(map[string]interface{}{
                 "query":     query,
                 "variables": variables,
         })
 
         if err != nil {
-                return sponsors, fmt.Errorf("failed to marshal OpenCollective GraphQL request: %w", err)
+                return response, fmt.Errorf("failed to marshal OpenCollective GraphQL request: %w", err)
         }
 
-        req, err := http.NewRequest("POST", "https://api.opencollective.com/graphql/v2", bytes.NewBuffer(requestBody))
+        req, err := http.NewRequestWithContext(ctx, "POST", cfg.OpenCollectiveAPIURL, bytes.NewBuffer(requestBody))
         if err != nil {
-                return sponsors, fmt.Errorf("failed to create OpenCollective GraphQL request: %w", err)
+                return response, fmt.Errorf("failed to create OpenCollective GraphQL request: %w", err)
         }
 
+        // Queries do not change anything and can be retried
+        req = markRetryable(req)
+
         if cfg.OpenCollectiveKey != "" {
                 req.Header.Set("Api-Key", cfg.OpenCollectiveKey)
         }
@@ -115,68 +199,72 @@ func FetchOpenCollectiveSponsors(cfg config.Config) ([]Sponsor, error) {
 
         resp, err := client.Do(req)
         if err != nil {
-                return sponsors, fmt.Errorf("failed to execute OpenCollective GraphQL request: %w", err)
+                return response, fmt.Errorf("failed to execute OpenCollective GraphQL request: %w", err)
         }
         defer resp.Body.Close()
 
         if resp.StatusCode != http.StatusOK {
                 body, _ := io.ReadAll(resp.Body)
-                return sponsors, fmt.Errorf("OpenCollective GraphQL request failed with status %d: %s", resp.StatusCode, string(body))
+                return response, fmt.Errorf("OpenCollective GraphQL request failed with status %d: %s", resp.StatusCode, string(body))
         }
 
-        var response OpenCollectiveResponse
         if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-                return sponsors, fmt.Errorf("failed to decode OpenCollective GraphQL response: %w", err)
+                return response, fmt.Errorf("failed to decode OpenCollective GraphQL response: %w", err)
         }
 
-        // Process sponsors
-        for _, node := range response.Data.Account.Orders.Nodes {
-                // Skip inactive accounts
-                if !node.FromAccount.IsActive {
-                        continue
-                }
-
-                // Calculate monthly amount based on frequency
-                monthlyAmount := 0.0
-                switch node.Frequency {
-                case "MONTHLY":
-                        monthlyAmount = node.Amount.Value
-                case "YEARLY":
-                        monthlyAmount = node.Amount.Value / 12
-                case "ONE_TIME":
-                        // For one-time donations, we'll spread them over a year
-                        monthlyAmount = node.Amount.Value / 12
-                default:
-                        // Skip unknown frequencies
-                        continue
-                }
+        return response, nil
+}
 
-                // Create sponsor profile URL
-                profileURL := fmt.Sprintf("https://opencollective.com/%s", node.FromAccount.Slug)
-                if node.FromAccount.Website != "" {
-                        profileURL = node.FromAccount.Website
-                }
+// openCollectiveSponsorFromOrder converts an order into a Sponsor. It
+// returns false for orders that should not be shown.
+func openCollectiveSponsorFromOrder(cfg config.Config, node OpenCollectiveOrder) (Sponsor, bool) {
+        // Skip inactive accounts
+        if !node.FromAccount.IsActive {
+                return Sponsor{}, false
+        }
 
-                // Use company name if available
-                name := node.FromAccount.Name
-                if node.FromAccount.Company != "" {
-                        name = node.FromAccount.Company
+        // Calculate monthly amount based on frequency
+        monthlyAmount := 0.0
+        oneTimeAmount := 0.0
+        switch node.Frequency {
+        case "MONTHLY":
+                monthlyAmount = node.Amount.Value
+        case "YEARLY":
+                monthlyAmount = node.Amount.Value / 12
+        case "ONE_TIME":
+                // One-time donations follow the one-time policy
+                if !includeOneTime(cfg, parseTime(node.CreatedAt)) {
+                        return Sponsor{}, false
                 }
+                oneTimeAmount = node.Amount.Value
+        default:
+                // Skip unknown frequencies
+                return Sponsor{}, false
+        }
 
-                sponsor := Sponsor{
-                        ID:            node.FromAccount.ID,
-                        Name:          name,
-                        Login:         node.FromAccount.Slug,
-                        AvatarURL:     node.FromAccount.ImageURL,
-                        Link:          profileURL,
-                        Platform:      "opencollective",
-                        MonthlyAmount: monthlyAmount,
-                        CreatedAt:     node.CreatedAt,
-                        TierName:      node.Tier.Name,
-                }
+        // Create sponsor profile URL
+        profileURL := fmt.Sprintf("https://opencollective.com/%s", node.FromAccount.Slug)
+        if node.FromAccount.Website != "" {
+                profileURL = node.FromAccount.Website
+        }
 
-                sponsors = append(sponsors, sponsor)
+        // Use company name if available
+        name := node.FromAccount.Name
+        if node.FromAccount.Company != "" {
+                name = node.FromAccount.Company
         }
 
-        return sponsors, nil
+        return Sponsor{
+                ID:            node.FromAccount.ID,
+                Name:          name,
+                Login:         node.FromAccount.Slug,
+                AvatarURL:     node.FromAccount.ImageURL,
+                Link:          profileURL,
+                Platform:      "opencollective",
+                MonthlyAmount: monthlyAmount,
+                OneTimeAmount: oneTimeAmount,
+                CreatedAt:     node.CreatedAt,
+                TierName:      node.Tier.Name,
+                IsOneTime:     node.Frequency == "ONE_TIME",
+        }, true
 }
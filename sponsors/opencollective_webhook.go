@@ -0,0 +1,154 @@
+package sponsors
+
+import (
+        "fmt"
+        "strings"
+
+        "sponsorgen/config"
+)
+
+// OpenCollectiveWebhookEvent represents the payload of an OpenCollective
+// webhook. Member events carry the backer in Member, order and subscription
+// events in FromCollective.
+type OpenCollectiveWebhookEvent struct {
+        Type string `json:"type"`
+        Data struct {
+                Member *struct {
+                        Role             string                       `json:"role"`
+                        MemberCollective OpenCollectiveWebhookAccount `json:"memberCollective"`
+                } `json:"member"`
+                FromCollective *OpenCollectiveWebhookAccount `json:"fromCollective"`
+                Order          *struct {
+                        ID          int    `json:"id"`
+                        TotalAmount int    `json:"totalAmount"` // in cents
+                        Currency    string `json:"currency"`
+                        Interval    string `json:"interval"` // month, year or empty for one-time
+                        CreatedAt   string `json:"createdAt"`
+                        Tier        *struct {
+                                Name string `json:"name"`
+                        } `json:"tier"`
+                } `json:"order"`
+        } `json:"data"`
+}
+
+// OpenCollectiveWebhookAccount represents an account as sent in OpenCollective webhooks
+type OpenCollectiveWebhookAccount struct {
+        ID        int    `json:"id"`
+        Slug      string `json:"slug"`
+        Name      string `json:"name"`
+        Company   string `json:"company"`
+        Website   string `json:"website"`
+        Image     string `json:"image"`
+        CreatedAt string `json:"createdAt"`
+}
+
+// account returns the backer of the event
+func (e OpenCollectiveWebhookEvent) account() *OpenCollectiveWebhookAccount {
+        if e.Data.Member != nil {
+                return &e.Data.Member.MemberCollective
+        }
+        return e.Data.FromCollective
+}
+
+// order converts the event into the order representation used by the
+// GraphQL API
+func (e OpenCollectiveWebhookEvent) order(account OpenCollectiveWebhookAccount) OpenCollectiveOrder {
+        var order OpenCollectiveOrder
+        order.FromAccount.ID = fmt.Sprintf("%d", account.ID)
+        order.FromAccount.Name = account.Name
+        order.FromAccount.Slug = account.Slug
+        order.FromAccount.ImageURL = account.Image
+        order.FromAccount.Website = account.Website
+        order.FromAccount.Company = account.Company
+        order.FromAccount.IsActive = true
+        order.FromAccount.CreatedAt = account.CreatedAt
+
+        if e.Data.Order != nil {
+                order.Amount.Value = float64(e.Data.Order.TotalAmount) / 100.0
+                order.Amount.Currency = e.Data.Order.Currency
+                order.CreatedAt = e.Data.Order.CreatedAt
+                if e.Data.Order.Tier != nil {
+                        order.Tier.Name = e.Data.Order.Tier.Name
+                }
+
+                switch e.Data.Order.Interval {
+                case "month":
+                        order.Frequency = "MONTHLY"
+                case "year":
+                        order.Frequency = "YEARLY"
+                default:
+                        order.Frequency = "ONE_TIME"
+                }
+        }
+
+        return order
+}
+
+// ApplyOpenCollectiveEvent applies an OpenCollective collective.member.created,
+// order.processed or subscription.canceled webhook to a list of sponsors
+// fetched by FetchAll. Backers are matched by slug, since webhooks use
+// different account IDs than the GraphQL API, and by whether the order is
+// recurring: a one-time donation is added next to a backer's subscription
+// instead of replacing it. It returns the updated list and whether anything
+// changed.
+func ApplyOpenCollectiveEvent(cfg config.Config, list []Sponsor, event OpenCollectiveWebhookEvent) ([]Sponsor, bool) {
+        account := event.account()
+        if account == nil || account.Slug == "" {
+                return list, false
+        }
+
+        switch event.Type {
+        case "collective.member.created", "order.processed":
+                // Members without an order, such as admins, are not backers
+                if event.Data.Order == nil {
+                        return list, false
+                }
+
+                sponsor, ok := openCollectiveSponsorFromOrder(cfg, event.order(*account))
+                if !ok {
+                        return list, false
+                }
+                sponsor.Status = StatusActive
+
+                if sponsor.IsOneTime {
+                        // Both the member and the order event report a first
+                        // donation, so one-time entries are keyed by order
+                        if event.Data.Order.ID != 0 {
+                                sponsor.ID = fmt.Sprintf("%s-order-%d", sponsor.ID, event.Data.Order.ID)
+                                if indexOfSponsor(list, "opencollective", sponsor.ID) >= 0 {
+                                        return list, false
+                                }
+                        }
+                        return append(list, sponsor), true
+                }
+
+                if index := indexOfOpenCollectiveSubscription(list, account.Slug); index >= 0 {
+                        sponsor.ID = list[index].ID
+                        list[index] = sponsor
+                } else {
+                        list = append(list, sponsor)
+                }
+                return list, true
+
+        case "subscription.canceled":
+                index := indexOfOpenCollectiveSubscription(list, account.Slug)
+                if index < 0 {
+                        return list, false
+                }
+                return append(list[:index], list[index+1:]...), true
+
+        default:
+                return list, false
+        }
+}
+
+// indexOfOpenCollectiveSubscription returns the index of the backer's
+// recurring entry in list, or -1 if there is none
+func indexOfOpenCollectiveSubscription(list []Sponsor, slug string) int {
+        for i, existing := range list {
+                if existing.Platform == "opencollective" && !existing.IsOneTime && strings.EqualFold(existing.Login, slug) {
+                        return i
+                }
+        }
+        return -1
+}
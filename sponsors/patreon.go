@@ -1,6 +1,7 @@
 package sponsors
 
 import (
+        "context"
         "encoding/json"
         "fmt"
         "io"
@@ -10,59 +11,96 @@ import (
         "sponsorgen/config"
 )
 
+func init() {
+        Register(patreonProvider{})
+}
+
+// patreonProvider fetches patrons from a Patreon campaign
+type patreonProvider struct{}
+
+func (patreonProvider) Name() string { return "patreon" }
+
+func (patreonProvider) Enabled(cfg config.Config) bool {
+        return cfg.PatreonToken != "" && cfg.PatreonCampaignID != ""
+}
+
+func (patreonProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchPatreonSponsors(ctx, cfg)
+}
+
+// patreonPlaceholderAvatar is used for patrons without a profile image
+const patreonPlaceholderAvatar = "https://c8.patreon.com/2/200/0"
+
 // PatreonResponse represents the Patreon API response
 type PatreonResponse struct {
-        Data []struct {
-                ID         string `json:"id"`
-                Attributes struct {
-                        FullName     string    `json:"full_name"`
-                        Email        string    `json:"email"`
-                        CreatedAt    time.Time `json:"created"`
-                        PatronStatus string    `json:"patron_status"`
-                        LastChargeDate   *time.Time `json:"last_charge_date"`
-                        LastChargeStatus string     `json:"last_charge_status"`
-                        LifetimeSupportCents int    `json:"lifetime_support_cents"`
-                        CurrentlyEntitledAmountCents int `json:"currently_entitled_amount_cents"`
-                        PledgeRelationshipStart *time.Time `json:"pledge_relationship_start"`
-                } `json:"attributes"`
-                Relationships struct {
-                        Currently_entitled_tiers struct {
-                                Data []struct {
-                                        ID   string `json:"id"`
-                                        Type string `json:"type"`
-                                } `json:"data"`
-                        } `json:"currently_entitled_tiers"`
-                } `json:"relationships"`
-                Type string `json:"type"`
-        } `json:"data"`
-        Included []struct {
-                ID         string `json:"id"`
-                Attributes struct {
-                        Title       string `json:"title"`
-                        Description string `json:"description"`
-                        AmountCents int    `json:"amount_cents"`
-                } `json:"attributes"`
-                Type string `json:"type"`
-        } `json:"included"`
-        Links struct {
+        Data     []PatreonMember   `json:"data"`
+        Included []PatreonIncluded `json:"included"`
+        Links    struct {
                 Next string `json:"next"`
         } `json:"links"`
 }
 
+// PatreonMember represents a member of a Patreon campaign
+type PatreonMember struct {
+        ID         string `json:"id"`
+        Attributes struct {
+                FullName     string    `json:"full_name"`
+                Email        string    `json:"email"`
+                CreatedAt    time.Time `json:"created"`
+                PatronStatus string    `json:"patron_status"`
+                LastChargeDate   *time.Time `json:"last_charge_date"`
+                LastChargeStatus string     `json:"last_charge_status"`
+                LifetimeSupportCents int    `json:"lifetime_support_cents"`
+                CurrentlyEntitledAmountCents int `json:"currently_entitled_amount_cents"`
+                PledgeRelationshipStart *time.Time `json:"pledge_relationship_start"`
+        } `json:"attributes"`
+        Relationships struct {
+                Currently_entitled_tiers struct {
+                        Data []struct {
+                                ID   string `json:"id"`
+                                Type string `json:"type"`
+                        } `json:"data"`
+                } `json:"currently_entitled_tiers"`
+                User struct {
+                        Data struct {
+                                ID   string `json:"id"`
+                                Type string `json:"type"`
+                        } `json:"data"`
+                } `json:"user"`
+        } `json:"relationships"`
+        Type string `json:"type"`
+}
+
+// PatreonIncluded represents an included resource, either a tier or a user
+type PatreonIncluded struct {
+        ID         string `json:"id"`
+        Attributes struct {
+                // Tier attributes
+                Title       string `json:"title"`
+                Description string `json:"description"`
+                AmountCents int    `json:"amount_cents"`
+
+                // User attributes
+                ImageURL string `json:"image_url"`
+                ThumbURL string `json:"thumb_url"`
+                Vanity   string `json:"vanity"`
+                URL      string `json:"url"`
+        } `json:"attributes"`
+        Type string `json:"type"`
+}
+
 // FetchPatreonSponsors fetches sponsors from Patreon
-func FetchPatreonSponsors(cfg config.Config) ([]Sponsor, error) {
+func FetchPatreonSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
         sponsors := []Sponsor{}
 
-        client := &http.Client{
-                Timeout: 10 * time.Second,
-        }
+        client := newHTTPClient(cfg, 10*time.Second)
 
         // Build URL with campaign ID
-        url := fmt.Sprintf("https://www.patreon.com/api/oauth2/v2/campaigns/%s/members?include=currently_entitled_tiers&fields[member]=full_name,email,patron_status,last_charge_date,last_charge_status,lifetime_support_cents,currently_entitled_amount_cents,pledge_relationship_start&fields[tier]=title,description,amount_cents", cfg.PatreonCampaignID)
+        url := fmt.Sprintf("%s/campaigns/%s/members?include=currently_entitled_tiers,user&fields[member]=full_name,email,patron_status,last_charge_date,last_charge_status,lifetime_support_cents,currently_entitled_amount_cents,pledge_relationship_start&fields[tier]=title,description,amount_cents&fields[user]=image_url,thumb_url,vanity,url", cfg.PatreonAPIURL, cfg.PatreonCampaignID)
 
         hasNextPage := true
         for hasNextPage {
-                req, err := http.NewRequest("GET", url, nil)
+                req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
                 if err != nil {
                         return sponsors, fmt.Errorf("failed to create Patreon API request: %w", err)
                 }
@@ -87,57 +125,13 @@ func FetchPatreonSponsors(cfg config.Config) ([]Sponsor, error) {
 
                 // Process sponsors
                 for _, patron := range response.Data {
-                        // Skip patrons who are not active
-                        if patron.Attributes.PatronStatus != "active_patron" {
+                        status, ok := patreonMemberStatus(cfg, patron, time.Now())
+                        if !ok {
                                 continue
                         }
 
-                        // Get monthly amount in dollars
-                        monthlyAmount := float64(patron.Attributes.CurrentlyEntitledAmountCents) / 100.0
-
-                        // Extract tier name
-                        tierName := ""
-                        if len(patron.Relationships.Currently_entitled_tiers.Data) > 0 {
-                                tierID := patron.Relationships.Currently_entitled_tiers.Data[0].ID
-                                for _, tier := range response.Included {
-                                        if tier.ID == tierID && tier.Type == "tier" {
-                                                tierName = tier.Attributes.Title
-                                                break
-                                        }
-                                }
-                        }
-
-                        // Use member ID as login if it's a public profile
-                        login := patron.ID
-                        // Name defaults to "Anonymous" if not provided
-                        name := patron.Attributes.FullName
-                        if name == "" {
-                                name = "Anonymous Patron"
-                        }
-
-                        // For avatar URL, we use a placeholder since Patreon doesn't provide avatars in the API
-                        avatarURL := "https://c8.patreon.com/2/200/0"
-
-                        // For link, use a Patreon profile URL if available (this is simplified)
-                        link := fmt.Sprintf("https://www.patreon.com/user?u=%s", patron.ID)
-
-                        createdAt := time.Now().Format(time.RFC3339)
-                        if patron.Attributes.PledgeRelationshipStart != nil {
-                                createdAt = patron.Attributes.PledgeRelationshipStart.Format(time.RFC3339)
-                        }
-
-                        sponsor := Sponsor{
-                                ID:            patron.ID,
-                                Name:          name,
-                                Login:         login,
-                                AvatarURL:     avatarURL,
-                                Link:          link,
-                                Platform:      "patreon",
-                                MonthlyAmount: monthlyAmount,
-                                CreatedAt:     createdAt,
-                                TierName:      tierName,
-                        }
-
+                        sponsor := patreonSponsorFromMember(patron, response.Included)
+                        sponsor.Status = status
                         sponsors = append(sponsors, sponsor)
                 }
 
@@ -150,3 +144,103 @@ func FetchPatreonSponsors(cfg config.Config) ([]Sponsor, error) {
 
         return sponsors, nil
 }
+
+// patreonMemberStatus determines the status of a member and whether it
+// should be shown. Active patrons are always shown. Declined patrons are
+// kept for PatreonGraceDays after the declined charge, former patrons for
+// PatreonGraceDays after the month covered by their last charge.
+func patreonMemberStatus(cfg config.Config, patron PatreonMember, now time.Time) (string, bool) {
+        grace := time.Duration(cfg.PatreonGraceDays) * 24 * time.Hour
+        lastCharge := patron.Attributes.LastChargeDate
+
+        switch patron.Attributes.PatronStatus {
+        case "active_patron":
+                return StatusActive, true
+        case "declined_patron":
+                if grace > 0 && lastCharge != nil && now.Sub(*lastCharge) <= grace {
+                        return StatusDeclined, true
+                }
+        case "former_patron":
+                if grace > 0 && lastCharge != nil && patron.Attributes.LastChargeStatus == "Paid" &&
+                        now.Sub(lastCharge.AddDate(0, 1, 0)) <= grace {
+                        return StatusLapsed, true
+                }
+        }
+
+        return "", false
+}
+
+// patreonSponsorFromMember converts a campaign member into a Sponsor, using
+// the included tier and user resources for the tier name and profile
+func patreonSponsorFromMember(patron PatreonMember, included []PatreonIncluded) Sponsor {
+        // Get monthly amount in dollars
+        monthlyAmount := float64(patron.Attributes.CurrentlyEntitledAmountCents) / 100.0
+
+        // Extract tier name
+        tierName := ""
+        if len(patron.Relationships.Currently_entitled_tiers.Data) > 0 {
+                tierID := patron.Relationships.Currently_entitled_tiers.Data[0].ID
+                if tier, ok := findPatreonIncluded(included, "tier", tierID); ok {
+                        tierName = tier.Attributes.Title
+                }
+        }
+
+        // Name defaults to "Anonymous" if not provided
+        name := patron.Attributes.FullName
+        if name == "" {
+                name = "Anonymous Patron"
+        }
+
+        // Fall back to the member ID and a placeholder when the user is not included
+        login := patron.ID
+        avatarURL := patreonPlaceholderAvatar
+        link := fmt.Sprintf("https://www.patreon.com/user?u=%s", patron.ID)
+
+        userID := patron.Relationships.User.Data.ID
+        if user, ok := findPatreonIncluded(included, "user", userID); ok {
+                login = userID
+                link = fmt.Sprintf("https://www.patreon.com/user?u=%s", userID)
+
+                if user.Attributes.Vanity != "" {
+                        login = user.Attributes.Vanity
+                }
+                if user.Attributes.URL != "" {
+                        link = user.Attributes.URL
+                }
+                if user.Attributes.ThumbURL != "" {
+                        avatarURL = user.Attributes.ThumbURL
+                } else if user.Attributes.ImageURL != "" {
+                        avatarURL = user.Attributes.ImageURL
+                }
+        }
+
+        createdAt := time.Now().Format(time.RFC3339)
+        if patron.Attributes.PledgeRelationshipStart != nil {
+                createdAt = patron.Attributes.PledgeRelationshipStart.Format(time.RFC3339)
+        }
+
+        return Sponsor{
+                ID:            patron.ID,
+                Name:          name,
+                Login:         login,
+                AvatarURL:     avatarURL,
+                Link:          link,
+                Platform:      "patreon",
+                MonthlyAmount: monthlyAmount,
+                CreatedAt:     createdAt,
+                TierName:      tierName,
+        }
+}
+
+// findPatreonIncluded looks up an included resource by type and ID
+func findPatreonIncluded(included []PatreonIncluded, resourceType, id string) (PatreonIncluded, bool) {
+        if id == "" {
+                return PatreonIncluded{}, false
+        }
+        for _, resource := range included {
+                if resource.Type == resourceType && resource.ID == id {
+                        return resource, true
+                }
+        }
+        return PatreonIncluded{}, false
+}
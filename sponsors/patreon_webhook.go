@@ -0,0 +1,56 @@
+package sponsors
+
+import (
+        "time"
+
+        "sponsorgen/config"
+)
+
+// PatreonWebhookEvent represents the payload of a Patreon members webhook,
+// a single member with its tiers and user included
+type PatreonWebhookEvent struct {
+        Data     PatreonMember     `json:"data"`
+        Included []PatreonIncluded `json:"included"`
+}
+
+// ApplyPatreonMemberEvent applies a Patreon members:pledge:create, update or
+// delete webhook to a list of sponsors fetched by FetchAll. It returns the
+// updated list and whether anything changed.
+func ApplyPatreonMemberEvent(cfg config.Config, list []Sponsor, eventType string, event PatreonWebhookEvent) ([]Sponsor, bool) {
+        member := event.Data
+        if member.ID == "" {
+                return list, false
+        }
+
+        switch eventType {
+        case "members:pledge:create", "members:pledge:update", "members:create", "members:update":
+        case "members:pledge:delete", "members:delete":
+                // The payload may still describe the pledge as it was before
+                // the deletion
+                if member.Attributes.PatronStatus == "active_patron" {
+                        member.Attributes.PatronStatus = "former_patron"
+                }
+        default:
+                return list, false
+        }
+
+        index := indexOfSponsor(list, "patreon", member.ID)
+
+        status, ok := patreonMemberStatus(cfg, member, time.Now())
+        if !ok {
+                if index < 0 {
+                        return list, false
+                }
+                return append(list[:index], list[index+1:]...), true
+        }
+
+        sponsor := patreonSponsorFromMember(member, event.Included)
+        sponsor.Status = status
+
+        if index >= 0 {
+                list[index] = sponsor
+        } else {
+                list = append(list, sponsor)
+        }
+        return list, true
+}
@@ -0,0 +1,140 @@
+package sponsors
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "io"
+        "net/http"
+        "net/url"
+        "time"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(polarProvider{})
+}
+
+// polarProvider fetches subscribers from Polar.sh
+type polarProvider struct{}
+
+func (polarProvider) Name() string { return "polar" }
+
+func (polarProvider) Enabled(cfg config.Config) bool {
+        return cfg.PolarToken != "" && cfg.PolarOrganizationID != ""
+}
+
+func (polarProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchPolarSponsors(ctx, cfg)
+}
+
+// PolarSubscriptionsResponse represents a page of Polar subscriptions
+type PolarSubscriptionsResponse struct {
+        Items      []PolarSubscription `json:"items"`
+        Pagination struct {
+                TotalCount int `json:"total_count"`
+                MaxPage    int `json:"max_page"`
+        } `json:"pagination"`
+}
+
+// PolarSubscription represents a subscription to a Polar product
+type PolarSubscription struct {
+        ID                string `json:"id"`
+        CreatedAt         string `json:"created_at"`
+        StartedAt         string `json:"started_at"`
+        Status            string `json:"status"`
+        Amount            int    `json:"amount"` // in cents
+        Currency          string `json:"currency"`
+        RecurringInterval string `json:"recurring_interval"` // month or year
+        Customer          struct {
+                ID        string `json:"id"`
+                Name      string `json:"name"`
+                AvatarURL string `json:"avatar_url"`
+        } `json:"customer"`
+        Product struct {
+                ID   string `json:"id"`
+                Name string `json:"name"`
+        } `json:"product"`
+}
+
+// FetchPolarSponsors fetches the active subscriptions of a Polar organization
+func FetchPolarSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        sponsors := []Sponsor{}
+
+        client := newHTTPClient(cfg, 10*time.Second)
+
+        for page := 1; ; page++ {
+                query := url.Values{}
+                query.Set("organization_id", cfg.PolarOrganizationID)
+                query.Set("active", "true")
+                query.Set("limit", "100")
+                query.Set("page", fmt.Sprintf("%d", page))
+
+                req, err := http.NewRequestWithContext(ctx, "GET", cfg.PolarAPIURL+"/subscriptions/?"+query.Encode(), nil)
+                if err != nil {
+                        return sponsors, fmt.Errorf("failed to create Polar API request: %w", err)
+                }
+
+                req.Header.Set("Authorization", "Bearer "+cfg.PolarToken)
+                req.Header.Set("Accept", "application/json")
+
+                resp, err := client.Do(req)
+                if err != nil {
+                        return sponsors, fmt.Errorf("failed to execute Polar API request: %w", err)
+                }
+
+                if resp.StatusCode != http.StatusOK {
+                        body, _ := io.ReadAll(resp.Body)
+                        resp.Body.Close()
+                        return sponsors, fmt.Errorf("Polar API request failed with status %d: %s", resp.StatusCode, string(body))
+                }
+
+                var response PolarSubscriptionsResponse
+                err = json.NewDecoder(resp.Body).Decode(&response)
+                resp.Body.Close()
+                if err != nil {
+                        return sponsors, fmt.Errorf("failed to decode Polar API response: %w", err)
+                }
+
+                for _, subscription := range response.Items {
+                        sponsors = append(sponsors, polarSponsorFromSubscription(cfg, subscription))
+                }
+
+                if page >= response.Pagination.MaxPage || len(response.Items) == 0 {
+                        break
+                }
+        }
+
+        return sponsors, nil
+}
+
+// polarSponsorFromSubscription converts a Polar subscription into a Sponsor
+func polarSponsorFromSubscription(cfg config.Config, subscription PolarSubscription) Sponsor {
+        monthlyAmount := float64(subscription.Amount) / 100.0
+        if subscription.RecurringInterval == "year" {
+                monthlyAmount /= 12
+        }
+
+        name := subscription.Customer.Name
+        if name == "" {
+                name = "Polar Subscriber"
+        }
+
+        createdAt := subscription.StartedAt
+        if createdAt == "" {
+                createdAt = subscription.CreatedAt
+        }
+
+        return Sponsor{
+                ID:            subscription.Customer.ID,
+                Name:          name,
+                Login:         subscription.Customer.ID,
+                AvatarURL:     AvatarOrDefault(subscription.Customer.AvatarURL, cfg),
+                Platform:      "polar",
+                MonthlyAmount: monthlyAmount,
+                CreatedAt:     createdAt,
+                TierName:      subscription.Product.Name,
+                TierID:        subscription.Product.ID,
+        }
+}
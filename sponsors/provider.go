@@ -0,0 +1,168 @@
+package sponsors
+
+import (
+        "context"
+        "fmt"
+        "log"
+        "sort"
+        "strings"
+        "sync"
+        "time"
+
+        "sponsorgen/config"
+)
+
+// Provider is a source of sponsors, such as GitHub Sponsors or Patreon
+type Provider interface {
+        // Name returns the platform identifier, e.g. "github"
+        Name() string
+        // Enabled reports whether the provider is configured
+        Enabled(cfg config.Config) bool
+        // Fetch retrieves the current sponsors from the platform
+        Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error)
+}
+
+var (
+        registryMutex sync.RWMutex
+        registry      []Provider
+)
+
+// Register adds a provider to the registry. It panics if a provider with
+// the same name has already been registered.
+func Register(provider Provider) {
+        registryMutex.Lock()
+        defer registryMutex.Unlock()
+
+        for _, existing := range registry {
+                if existing.Name() == provider.Name() {
+                        panic(fmt.Sprintf("sponsors: provider %q registered twice", provider.Name()))
+                }
+        }
+
+        registry = append(registry, provider)
+}
+
+// Providers returns all registered providers in registration order
+func Providers() []Provider {
+        registryMutex.RLock()
+        defer registryMutex.RUnlock()
+
+        result := make([]Provider, len(registry))
+        copy(result, registry)
+        return result
+}
+
+// EnabledProviders returns the registered providers that are configured in cfg
+func EnabledProviders(cfg config.Config) []Provider {
+        var enabled []Provider
+        for _, provider := range Providers() {
+                if provider.Enabled(cfg) {
+                        enabled = append(enabled, provider)
+                }
+        }
+        return enabled
+}
+
+// providerTimeout returns how long a provider may take to fetch its sponsors
+func providerTimeout(cfg config.Config, name string) time.Duration {
+        seconds, ok := cfg.ProviderTimeouts[name]
+        if !ok || seconds <= 0 {
+                seconds = cfg.ProviderTimeoutSeconds
+        }
+        if seconds <= 0 {
+                seconds = 60
+        }
+        return time.Duration(seconds) * time.Second
+}
+
+// StaleProvider is a provider whose fetch failed and whose cached sponsors
+// were used instead
+type StaleProvider struct {
+        Platform string `json:"platform"`
+        Since    string `json:"since"`
+        Error    string `json:"error"`
+}
+
+// FetchAll fetches sponsors from every enabled provider concurrently.
+// Each provider is limited to its configured timeout and all of them stop
+// when ctx is cancelled. Failures of individual providers are logged and
+// the sponsors from the remaining providers are returned; an error is only
+// returned when no sponsors could be fetched at all. The result of every
+// successful fetch is cached, and a failed provider falls back to its
+// cached sponsors, marked as stale, as long as they are not older than
+// ProviderCacheMaxAgeHours. Such providers are reported in the returned
+// stale list, sorted by platform.
+func FetchAll(ctx context.Context, cfg config.Config) ([]Sponsor, []StaleProvider, error) {
+        var allSponsors []Sponsor
+        var staleProviders []StaleProvider
+        var wg sync.WaitGroup
+        var mu sync.Mutex
+        var errors []error
+
+        for _, provider := range EnabledProviders(cfg) {
+                wg.Add(1)
+                go func(provider Provider) {
+                        defer wg.Done()
+                        providerCtx, cancel := context.WithTimeout(ctx, providerTimeout(cfg, provider.Name()))
+                        defer cancel()
+
+                        var staleProvider *StaleProvider
+                        fetched, err := provider.Fetch(providerCtx, cfg)
+                        if err == nil {
+                                for i := range fetched {
+                                        if fetched[i].Status == "" {
+                                                fetched[i].Status = StatusActive
+                                        }
+                                }
+                                if cfg.ProviderCacheMaxAgeHours > 0 {
+                                        if cacheErr := saveProviderCache(cfg, provider.Name(), fetched); cacheErr != nil {
+                                                log.Printf("Warning: failed to cache %s sponsors: %v", provider.Name(), cacheErr)
+                                        }
+                                }
+                        } else if stale, fetchedAt, ok := loadStaleSponsors(cfg, provider.Name()); ok {
+                                log.Printf("Warning: using %s sponsors cached at %s: %v", provider.Name(), fetchedAt.Format(time.RFC3339), err)
+                                staleProvider = &StaleProvider{
+                                        Platform: provider.Name(),
+                                        Since:    fetchedAt.Format(time.RFC3339),
+                                        Error:    err.Error(),
+                                }
+                                fetched, err = stale, nil
+                        }
+
+                        mu.Lock()
+                        defer mu.Unlock()
+                        if err != nil {
+                                errors = append(errors, fmt.Errorf("%s sponsors: %w", provider.Name(), err))
+                                return
+                        }
+                        allSponsors = append(allSponsors, fetched...)
+                        if staleProvider != nil {
+                                staleProviders = append(staleProviders, *staleProvider)
+                        }
+                }(provider)
+        }
+
+        // Wait for all fetchers to complete
+        wg.Wait()
+
+        sort.Slice(staleProviders, func(i, j int) bool {
+                return staleProviders[i].Platform < staleProviders[j].Platform
+        })
+
+        // Check for errors
+        if len(errors) > 0 {
+                messages := make([]string, 0, len(errors))
+                for _, err := range errors {
+                        messages = append(messages, err.Error())
+                }
+                errorMsg := "errors fetching sponsors:\n- " + strings.Join(messages, "\n- ")
+
+                // If we have some sponsors, continue despite errors
+                if len(allSponsors) == 0 {
+                        return nil, nil, fmt.Errorf("%s", errorMsg)
+                }
+                log.Println(errorMsg)
+        }
+
+        return allSponsors, staleProviders, nil
+}
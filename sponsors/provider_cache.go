@@ -0,0 +1,80 @@
+package sponsors
+
+import (
+        "encoding/json"
+        "fmt"
+        "os"
+        "path/filepath"
+        "time"
+
+        "sponsorgen/config"
+)
+
+// ProviderCache is the last successful result of a provider, kept as a
+// fallback for when the provider fails
+type ProviderCache struct {
+        FetchedAt string    `json:"fetchedAt"`
+        Sponsors  []Sponsor `json:"sponsors"`
+}
+
+// providerCachePath returns the path of a provider's last-known-good cache
+func providerCachePath(cfg config.Config, name string) string {
+        return filepath.Join(cfg.CacheDir, fmt.Sprintf("provider_%s.json", name))
+}
+
+// saveProviderCache writes the sponsors of a successful fetch atomically
+func saveProviderCache(cfg config.Config, name string, sponsors []Sponsor) error {
+        cache := ProviderCache{
+                FetchedAt: time.Now().Format(time.RFC3339),
+                Sponsors:  sponsors,
+        }
+
+        data, err := json.MarshalIndent(cache, "", "  ")
+        if err != nil {
+                return fmt.Errorf("encoding provider cache: %w", err)
+        }
+
+        path := providerCachePath(cfg, name)
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+                return fmt.Errorf("creating cache directory: %w", err)
+        }
+
+        tmpPath := path + ".tmp"
+        if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+                return fmt.Errorf("writing provider cache: %w", err)
+        }
+
+        return os.Rename(tmpPath, path)
+}
+
+// loadStaleSponsors returns the cached sponsors of a provider marked as
+// stale. It returns false if there is no cache or it is older than
+// ProviderCacheMaxAgeHours.
+func loadStaleSponsors(cfg config.Config, name string) ([]Sponsor, time.Time, bool) {
+        if cfg.ProviderCacheMaxAgeHours <= 0 {
+                return nil, time.Time{}, false
+        }
+
+        data, err := os.ReadFile(providerCachePath(cfg, name))
+        if err != nil {
+                return nil, time.Time{}, false
+        }
+
+        var cache ProviderCache
+        if err := json.Unmarshal(data, &cache); err != nil {
+                return nil, time.Time{}, false
+        }
+
+        fetchedAt := parseTime(cache.FetchedAt)
+        maxAge := time.Duration(cfg.ProviderCacheMaxAgeHours) * time.Hour
+        if fetchedAt.IsZero() || time.Since(fetchedAt) > maxAge {
+                return nil, fetchedAt, false
+        }
+
+        for i := range cache.Sponsors {
+                cache.Sponsors[i].Stale = true
+                cache.Sponsors[i].StaleSince = cache.FetchedAt
+        }
+
+        return cache.Sponsors, fetchedAt, true
+}
@@ -2,21 +2,54 @@ package sponsors
 
 import (
         "strings"
+        "time"
 
         "sponsorgen/config"
 )
 
+// Sponsor statuses
+const (
+        StatusActive   = "active"   // currently sponsoring
+        StatusPast     = "past"     // sponsored in the past but has since stopped
+        StatusDeclined = "declined" // last payment was declined, kept during the grace period
+        StatusLapsed   = "lapsed"   // recently stopped, kept during the grace period
+)
+
 // Sponsor represents a sponsor from any platform
 type Sponsor struct {
-        ID            string  `json:"id"`
-        Name          string  `json:"name"`
-        Login         string  `json:"login"`
-        AvatarURL     string  `json:"avatarUrl"`
-        Link          string  `json:"link"`
-        Platform      string  `json:"platform"` // github, opencollective, patreon, afdian
-        MonthlyAmount float64 `json:"monthlyAmount"`
-        CreatedAt     string  `json:"createdAt"`
-        TierName      string  `json:"tierName,omitempty"`
+        ID             string  `json:"id"`
+        Name           string  `json:"name"`
+        Login          string  `json:"login"`
+        AvatarURL      string  `json:"avatarUrl"`
+        Link           string  `json:"link"`
+        Platform       string  `json:"platform"` // github, opencollective, patreon, afdian, kofi, liberapay, buymeacoffee, polar, stripe, manual, command
+        MonthlyAmount  float64 `json:"monthlyAmount"`
+        OneTimeAmount  float64 `json:"oneTimeAmount,omitempty"`  // total of included one-time payments
+        LifetimeAmount float64 `json:"lifetimeAmount,omitempty"` // total of all payments, where known
+        LastPaymentAt  string  `json:"lastPaymentAt,omitempty"`
+        CreatedAt      string  `json:"createdAt"`
+        Months         int     `json:"months,omitempty"` // number of months paid for, where known
+        TierName       string  `json:"tierName,omitempty"`
+        TierID         string  `json:"tierId,omitempty"`
+        IsOneTime      bool    `json:"isOneTime,omitempty"`      // one-time payment rather than recurring
+        IsCustomAmount bool    `json:"isCustomAmount,omitempty"` // amount chosen by the sponsor instead of a fixed tier
+        Status         string  `json:"status"`                   // one of the Status* constants
+        Stale          bool    `json:"stale,omitempty"`          // served from the provider cache because the last fetch failed
+        StaleSince     string  `json:"staleSince,omitempty"`     // when the cached data was fetched
+}
+
+// IsPast reports whether the sponsor is no longer sponsoring
+func (s Sponsor) IsPast() bool {
+        return s.Status == StatusPast
+}
+
+// AvatarOrDefault returns avatarURL, or the configured default avatar if the
+// platform did not provide one
+func AvatarOrDefault(avatarURL string, cfg config.Config) string {
+        if avatarURL == "" {
+                return cfg.DefaultAvatar
+        }
+        return avatarURL
 }
 
 // ApplyFilters applies exclusion and inclusion filters from the config
@@ -103,4 +136,39 @@ func SortSponsors(sponsors []Sponsor) []Sponsor {
         return result
 }
 
+// indexOfSponsor returns the index of the sponsor with the given platform
+// and ID in list, or -1 if there is none
+func indexOfSponsor(list []Sponsor, platform, id string) int {
+        for i, sponsor := range list {
+                if sponsor.Platform == platform && sponsor.ID == id {
+                        return i
+                }
+        }
+        return -1
+}
+
+// includeOneTime reports whether a one-time payment made at paidAt should be
+// shown according to the configured one-time policy
+func includeOneTime(cfg config.Config, paidAt time.Time) bool {
+        switch cfg.OneTimePolicy {
+        case config.OneTimePermanent:
+                return true
+        case config.OneTimeRecent:
+                if paidAt.IsZero() {
+                        return false
+                }
+                return time.Since(paidAt) <= time.Duration(cfg.OneTimeDays)*24*time.Hour
+        default:
+                return false
+        }
+}
 
+// parseTime parses an RFC 3339 timestamp, returning the zero time if it is
+// empty or malformed
+func parseTime(value string) time.Time {
+        t, err := time.Parse(time.RFC3339, value)
+        if err != nil {
+                return time.Time{}
+        }
+        return t
+}
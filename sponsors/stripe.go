@@ -0,0 +1,196 @@
+package sponsors
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "io"
+        "net/http"
+        "net/url"
+        "strings"
+        "time"
+
+        "sponsorgen/config"
+)
+
+func init() {
+        Register(stripeProvider{})
+}
+
+// stripeProvider fetches subscribers from Stripe
+type stripeProvider struct{}
+
+func (stripeProvider) Name() string { return "stripe" }
+
+func (stripeProvider) Enabled(cfg config.Config) bool {
+        return cfg.StripeSecretKey != ""
+}
+
+func (stripeProvider) Fetch(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        return FetchStripeSponsors(ctx, cfg)
+}
+
+// Customer metadata keys understood by the Stripe provider
+const (
+        StripeMetadataName   = "sponsorgen_name"   // display name
+        StripeMetadataLogo   = "sponsorgen_logo"   // avatar or logo URL
+        StripeMetadataLink   = "sponsorgen_link"   // link target
+        StripeMetadataHidden = "sponsorgen_hidden" // "true" to opt out of public display
+)
+
+// StripeSubscriptionList represents a page of Stripe subscriptions
+type StripeSubscriptionList struct {
+        Data    []StripeSubscription `json:"data"`
+        HasMore bool                 `json:"has_more"`
+}
+
+// StripeSubscription represents a Stripe subscription with its customer expanded
+type StripeSubscription struct {
+        ID       string `json:"id"`
+        Status   string `json:"status"`
+        Created  int64  `json:"created"`
+        Customer struct {
+                ID       string            `json:"id"`
+                Name     string            `json:"name"`
+                Deleted  bool              `json:"deleted"`
+                Metadata map[string]string `json:"metadata"`
+        } `json:"customer"`
+        Items struct {
+                Data []struct {
+                        Quantity int `json:"quantity"`
+                        Price    struct {
+                                ID         string `json:"id"`
+                                Nickname   string `json:"nickname"`
+                                UnitAmount int64  `json:"unit_amount"` // in cents
+                                Currency   string `json:"currency"`
+                                Recurring  struct {
+                                        Interval      string `json:"interval"` // day, week, month or year
+                                        IntervalCount int    `json:"interval_count"`
+                                } `json:"recurring"`
+                        } `json:"price"`
+                } `json:"data"`
+        } `json:"items"`
+}
+
+// FetchStripeSponsors fetches active Stripe subscriptions. Customers are
+// displayed using their sponsorgen_* metadata and can opt out through
+// StripeMetadataHidden.
+func FetchStripeSponsors(ctx context.Context, cfg config.Config) ([]Sponsor, error) {
+        sponsors := []Sponsor{}
+
+        client := newHTTPClient(cfg, 10*time.Second)
+
+        startingAfter := ""
+        for {
+                query := url.Values{}
+                query.Set("status", "active")
+                query.Set("limit", "100")
+                query.Add("expand[]", "data.customer")
+                if startingAfter != "" {
+                        query.Set("starting_after", startingAfter)
+                }
+
+                req, err := http.NewRequestWithContext(ctx, "GET", cfg.StripeAPIURL+"/subscriptions?"+query.Encode(), nil)
+                if err != nil {
+                        return sponsors, fmt.Errorf("failed to create Stripe API request: %w", err)
+                }
+
+                req.Header.Set("Authorization", "Bearer "+cfg.StripeSecretKey)
+
+                resp, err := client.Do(req)
+                if err != nil {
+                        return sponsors, fmt.Errorf("failed to execute Stripe API request: %w", err)
+                }
+
+                if resp.StatusCode != http.StatusOK {
+                        body, _ := io.ReadAll(resp.Body)
+                        resp.Body.Close()
+                        return sponsors, fmt.Errorf("Stripe API request failed with status %d: %s", resp.StatusCode, string(body))
+                }
+
+                var response StripeSubscriptionList
+                err = json.NewDecoder(resp.Body).Decode(&response)
+                resp.Body.Close()
+                if err != nil {
+                        return sponsors, fmt.Errorf("failed to decode Stripe API response: %w", err)
+                }
+
+                for _, subscription := range response.Data {
+                        if sponsor, ok := stripeSponsorFromSubscription(subscription); ok {
+                                sponsors = append(sponsors, sponsor)
+                        }
+                }
+
+                if !response.HasMore || len(response.Data) == 0 {
+                        break
+                }
+                startingAfter = response.Data[len(response.Data)-1].ID
+        }
+
+        return sponsors, nil
+}
+
+// stripeSponsorFromSubscription converts a subscription into a Sponsor. It
+// returns false for deleted customers and those who opted out.
+func stripeSponsorFromSubscription(subscription StripeSubscription) (Sponsor, bool) {
+        customer := subscription.Customer
+        if customer.Deleted || strings.EqualFold(customer.Metadata[StripeMetadataHidden], "true") {
+                return Sponsor{}, false
+        }
+
+        // Normalize every item to a monthly amount
+        monthlyAmount := 0.0
+        tierName := ""
+        for _, item := range subscription.Items.Data {
+                quantity := item.Quantity
+                if quantity < 1 {
+                        quantity = 1
+                }
+                amount := float64(item.Price.UnitAmount) / 100.0 * float64(quantity)
+                monthlyAmount += stripeMonthlyAmount(amount, item.Price.Recurring.Interval, item.Price.Recurring.IntervalCount)
+
+                if tierName == "" {
+                        tierName = item.Price.Nickname
+                }
+        }
+
+        name := customer.Metadata[StripeMetadataName]
+        if name == "" {
+                name = customer.Name
+        }
+        if name == "" {
+                name = "Stripe Sponsor"
+        }
+
+        return Sponsor{
+                ID:            customer.ID,
+                Name:          name,
+                Login:         customer.ID,
+                AvatarURL:     customer.Metadata[StripeMetadataLogo],
+                Link:          customer.Metadata[StripeMetadataLink],
+                Platform:      "stripe",
+                MonthlyAmount: monthlyAmount,
+                CreatedAt:     time.Unix(subscription.Created, 0).Format(time.RFC3339),
+                TierName:      tierName,
+        }, true
+}
+
+// stripeMonthlyAmount converts an amount billed every intervalCount
+// intervals into a monthly amount
+func stripeMonthlyAmount(amount float64, interval string, intervalCount int) float64 {
+        if intervalCount < 1 {
+                intervalCount = 1
+        }
+        amount /= float64(intervalCount)
+
+        switch interval {
+        case "day":
+                return amount * 365 / 12
+        case "week":
+                return amount * weeksPerMonth
+        case "year":
+                return amount / 12
+        default:
+                return amount
+        }
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -11,8 +12,9 @@ import (
 	"time"
 )
 
-// DownloadImage downloads an image from a URL and returns it as a base64-encoded data URI
-func DownloadImage(imageURL string, cacheDir string) (string, error) {
+// DownloadImage downloads an image from a URL and returns it as a base64-encoded data URI.
+// The download is abandoned when ctx is cancelled.
+func DownloadImage(ctx context.Context, imageURL string, cacheDir string) (string, error) {
 	// Use cached version if available
 	cacheKey := getImageCacheKey(imageURL)
 	cachePath := filepath.Join(cacheDir, cacheKey)
@@ -51,7 +53,11 @@ func DownloadImage(imageURL string, cacheDir string) (string, error) {
 	
 	// Download the image
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(imageURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating image request: %w", err)
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("error downloading image: %w", err)
 	}